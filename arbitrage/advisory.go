@@ -0,0 +1,47 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/0xtrooper/flashbots_client"
+)
+
+// reportAdvisory ends an advisory run after the simulation. The opportunity is judged by the same profit checks as a
+// real run, printed and logged, but the bundle is never sent. Like --print-profit-only, a missing opportunity is
+// returned as error, so the exit code and the hooks notify only about profitable bundles.
+func reportAdvisory(ctx context.Context, logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, success bool, simulatedGas []uint64, expectedProfit, maxBundleFees, maxArbitrageFees *big.Int, minipools int) error {
+	var err error
+	if !success {
+		err = errors.New("bundle simulation failed")
+	} else {
+		err = checkProfit(dataIn, expectedProfit, maxBundleFees, maxArbitrageFees)
+		if err == nil && dataIn.AutoThreshold > 0 {
+			err = checkAutoThreshold(ctx, logger, dataIn, bundle, simulatedGas, expectedProfit)
+		}
+	}
+
+	netProfit := new(big.Int).Sub(expectedProfit, maxBundleFees)
+	logger.Info("advisory",
+		slog.Bool("opportunity", err == nil),
+		slog.Int("minipools", minipools),
+		slog.String("netProfit", weiToEthString(netProfit)),
+		slog.String("maxBundleFees", weiToEthString(maxBundleFees)),
+	)
+
+	if err != nil {
+		fmt.Print(colorOrange, "Advisory: no opportunity, ", err.Error(), ". Nothing was sent.", colorReset, "\n")
+		return errors.Join(errors.New("no opportunity"), err)
+	}
+
+	fmt.Print(colorGreen)
+	fmt.Printf("Advisory: opportunity with an expected profit after fees of %s ETH for %d minipools. Nothing was sent, run without --advisory to submit.\n",
+		formatEth(weiToEth(netProfit), dataIn.Precision, dataIn.Scientific),
+		minipools,
+	)
+	fmt.Print(colorReset, "\n")
+	return nil
+}
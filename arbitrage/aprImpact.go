@@ -0,0 +1,74 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// printAprImpact relates the profit after fees to the ETH bonded by the nodes of the minipools. The profit is
+// a one-off, counted over a year it adds the same number of bps to the effective APR of the bond.
+// Advisory only, errors are logged.
+func printAprImpact(ctx context.Context, logger *slog.Logger, dataIn *DataIn, minipools []common.Address, profit *big.Int) {
+	if profit.Sign() <= 0 {
+		return
+	}
+
+	bonded, err := getBondedEth(ctx, dataIn, minipools)
+	if err != nil {
+		logger.Warn("failed to get the bonded ETH for the APR impact", slog.String("error", err.Error()))
+		return
+	}
+	logger.Debug("apr impact", slog.String("bonded", bonded.String()), slog.String("profit", profit.String()))
+
+	if bonded.Sign() == 0 {
+		return
+	}
+
+	bps := basisPoints(profit, bonded)
+	fmt.Printf("APR impact: the profit after fees of %s ETH is %.2f bps of the %s ETH bonded by the node, about +%.2f bps on its effective APR over a year (one-off, advisory).\n\n",
+		formatEth(weiToEth(profit), dataIn.Precision, dataIn.Scientific),
+		bps,
+		formatEth(weiToEth(bonded), dataIn.Precision, dataIn.Scientific),
+		bps,
+	)
+}
+
+// getBondedEth sums the ETH provided by the nodes of the minipools before they are distributed
+func getBondedEth(ctx context.Context, dataIn *DataIn, minipools []common.Address) (*big.Int, error) {
+	nodes, _, err := groupMinipoolsByNode(ctx, dataIn, minipools)
+	if err != nil {
+		return nil, err
+	}
+
+	stakingAddress, err := getRocketpoolContractAddress(ctx, dataIn.Client, dataIn.NetworkId, "rocketNodeStaking", dataIn.Ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get node staking address"), err)
+	}
+
+	stakingABI, err := abi.JSON(strings.NewReader(RocketNodeStakingABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get node staking ABI"), err)
+	}
+
+	bonded := new(big.Int)
+	for _, node := range nodes {
+		values, err := callViewFunction(ctx, dataIn.Client, stakingABI, stakingAddress, dataIn.Ratelimit, "getNodeETHProvided", node)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := values[0].(*big.Int)
+		if !ok {
+			return nil, errors.New("unexpected getNodeETHProvided output")
+		}
+		bonded.Add(bonded, value)
+	}
+
+	return bonded, nil
+}
@@ -0,0 +1,75 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+)
+
+// checkAutoThreshold requires the expected profit to exceed the fees the bundle is expected to pay at the current
+// base fee, times the margin of --auto-threshold. Unlike the max fees used by checkProfit, the expected fees follow
+// the market, so the threshold is low in cheap gas periods and high in expensive ones.
+func checkAutoThreshold(ctx context.Context, logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, simulatedGas []uint64, expectedProfit *big.Int) error {
+	expectedFees, err := estimateBundleFees(ctx, dataIn, bundle, simulatedGas)
+	if err != nil {
+		return errors.Join(errors.New("failed to estimate the bundle fees for the auto threshold"), err)
+	}
+
+	threshold, _ := new(big.Float).Mul(new(big.Float).SetInt(expectedFees), big.NewFloat(dataIn.AutoThreshold)).Int(nil)
+	logger.Debug("auto threshold",
+		slog.String("expectedFees", expectedFees.String()),
+		slog.Float64("margin", dataIn.AutoThreshold),
+		slog.String("threshold", threshold.String()),
+	)
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Printf("Auto threshold: %s ETH (%.2fx the expected fees of %s ETH at the current base fee)\n\n",
+			formatEth(weiToEth(threshold), dataIn.Precision, dataIn.Scientific),
+			dataIn.AutoThreshold,
+			formatEth(weiToEth(expectedFees), dataIn.Precision, dataIn.Scientific),
+		)
+	}
+
+	if expectedProfit.Cmp(threshold) < 0 {
+		return fmt.Errorf("expected profit of %.6f ETH is below the auto threshold of %.6f ETH", weiToEth(expectedProfit), weiToEth(threshold))
+	}
+
+	return nil
+}
+
+// estimateBundleFees returns the fees of the bundle at the latest base fee, using the simulated gas of each tx.
+// The effective gas price is the base fee plus the tip, capped by the fee cap.
+func estimateBundleFees(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle, simulatedGas []uint64) (*big.Int, error) {
+	header, err := dataIn.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get latest block"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+	if header.BaseFee == nil {
+		return nil, errors.New("latest block has no base fee")
+	}
+
+	fees := new(big.Int)
+	for index, tx := range bundle.Transactions() {
+		gas := tx.Gas()
+		if index < len(simulatedGas) {
+			gas = simulatedGas[index]
+		}
+
+		gasPrice := new(big.Int).Add(header.BaseFee, tx.GasTipCap())
+		if gasPrice.Cmp(tx.GasFeeCap()) > 0 {
+			gasPrice = tx.GasFeeCap()
+		}
+
+		fees.Add(fees, new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas)))
+	}
+
+	return fees, nil
+}
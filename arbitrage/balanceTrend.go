@@ -0,0 +1,77 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// roughly one epoch, recent enough for the state to be available on a non-archive node
+const BALANCE_TREND_BLOCKS = 32
+
+// warnBalanceTrend compares the minipool balances at the latest block with the balances BALANCE_TREND_BLOCKS before.
+// A balance that grew in between is likely still receiving withdrawals, distributing now leaves the rest for a
+// later distribution. Advisory only, errors are logged.
+func warnBalanceTrend(ctx context.Context, logger *slog.Logger, dataIn DataIn, minipools []common.Address) {
+	latest, err := dataIn.Client.BlockNumber(ctx)
+	if err != nil {
+		logger.Warn("failed to get block number for the balance trend", slog.String("error", err.Error()))
+		return
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+	if latest < BALANCE_TREND_BLOCKS {
+		return
+	}
+
+	latestBlock := new(big.Int).SetUint64(latest)
+	earlierBlock := new(big.Int).SetUint64(latest - BALANCE_TREND_BLOCKS)
+	for _, minipoolAddress := range minipools {
+		if ctx.Err() != nil {
+			return
+		}
+
+		current, err := dataIn.Client.BalanceAt(ctx, minipoolAddress, latestBlock)
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		if err != nil {
+			logger.Warn("failed to get minipool balance for the balance trend", slog.String("minipool", minipoolAddress.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+
+		earlier, err := dataIn.Client.BalanceAt(ctx, minipoolAddress, earlierBlock)
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		if err != nil {
+			logger.Debug("failed to get earlier minipool balance, the rpc may have pruned the state", slog.String("minipool", minipoolAddress.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+
+		logger.Debug("minipool balance trend",
+			slog.String("minipool", minipoolAddress.Hex()),
+			slog.Uint64("block", latest),
+			slog.String("balance", current.String()),
+			slog.String("earlierBalance", earlier.String()),
+		)
+
+		if current.Cmp(earlier) <= 0 {
+			continue
+		}
+
+		fmt.Print(colorOrange)
+		fmt.Printf("Warning: the balance of minipool %s grew from %.4f to %.4f ETH in the last %d blocks, withdrawals may still be arriving. Consider waiting until it settles.\n",
+			minipoolAddress.Hex(),
+			weiToEth(earlier),
+			weiToEth(current),
+			BALANCE_TREND_BLOCKS,
+		)
+		fmt.Print(colorReset, "\n")
+	}
+}
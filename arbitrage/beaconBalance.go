@@ -0,0 +1,106 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"rocketpoolArbitrage/beaconchain"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// warnBeaconBalances cross-checks the minipools against their validators on the beacon chain. The distributed
+// ETH is the minipool balance, a validator that is still active or has a balance left that is not withdrawn
+// yet is not part of it and the profit estimate is lower than after the full withdrawal. Advisory only, errors are logged.
+func warnBeaconBalances(ctx context.Context, logger *slog.Logger, dataIn DataIn, minipools []common.Address) {
+	managerAddress, err := getRocketpoolContractAddress(ctx, dataIn.Client, dataIn.NetworkId, "rocketMinipoolManager", dataIn.Ratelimit)
+	if err != nil {
+		logger.Warn("failed to get minipool manager address for the beacon check", slog.String("error", err.Error()))
+		return
+	}
+
+	managerABI, err := abi.JSON(strings.NewReader(MinipoolManagerABI))
+	if err != nil {
+		logger.Warn("failed to get minipool manager ABI for the beacon check", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, minipoolAddress := range minipools {
+		if ctx.Err() != nil {
+			return
+		}
+
+		pubkey, err := getMinipoolPubkey(ctx, dataIn, managerABI, managerAddress, minipoolAddress)
+		if err != nil {
+			logger.Warn("failed to get minipool pubkey", slog.String("minipool", minipoolAddress.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+
+		status, beaconBalance, err := beaconchain.GetValidatorBalance(dataIn.BeaconUrl, pubkey)
+		if err != nil {
+			logger.Warn("failed to get validator balance", slog.String("minipool", minipoolAddress.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+
+		minipoolBalance, err := dataIn.Client.BalanceAt(ctx, minipoolAddress, nil)
+		if err != nil {
+			logger.Warn("failed to get minipool balance", slog.String("minipool", minipoolAddress.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		logger.Debug("beacon balance",
+			slog.String("minipool", minipoolAddress.Hex()),
+			slog.String("status", status),
+			slog.String("beaconBalance", beaconBalance.String()),
+			slog.String("minipoolBalance", minipoolBalance.String()),
+		)
+
+		printBeaconBalance(minipoolAddress, status, beaconBalance, minipoolBalance)
+	}
+}
+
+func printBeaconBalance(minipoolAddress common.Address, status string, beaconBalance, minipoolBalance *big.Int) {
+	switch {
+	case strings.HasPrefix(status, "pending") || strings.HasPrefix(status, "active"):
+		fmt.Print(colorOrange)
+		fmt.Printf("Warning: the validator of minipool %s is %s, its %.4f ETH are not withdrawn. Only the %.4f ETH on the minipool would be distributed.\n",
+			minipoolAddress.Hex(),
+			status,
+			weiToEth(beaconBalance),
+			weiToEth(minipoolBalance),
+		)
+		fmt.Print(colorReset, "\n")
+	case beaconBalance.Sign() > 0:
+		fmt.Print(colorOrange)
+		fmt.Printf("Warning: the validator of minipool %s is %s with %.4f ETH still pending withdrawal. The estimate only covers the %.4f ETH on the minipool, consider waiting for the withdrawal.\n",
+			minipoolAddress.Hex(),
+			status,
+			weiToEth(beaconBalance),
+			weiToEth(minipoolBalance),
+		)
+		fmt.Print(colorReset, "\n")
+	}
+}
+
+func getMinipoolPubkey(ctx context.Context, dataIn DataIn, managerABI abi.ABI, managerAddress, minipoolAddress common.Address) (string, error) {
+	values, err := callViewFunction(ctx, dataIn.Client, managerABI, managerAddress, dataIn.Ratelimit, "getMinipoolPubkey", minipoolAddress)
+	if err != nil {
+		return "", err
+	}
+
+	pubkey, ok := values[0].([]byte)
+	if !ok || len(pubkey) == 0 {
+		return "", errors.New("unexpected getMinipoolPubkey output")
+	}
+
+	return hexutil.Encode(pubkey), nil
+}
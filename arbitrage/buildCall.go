@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -36,15 +37,22 @@ const (
 	ARBITRAGE_PARASWAP_CALL_MAX_GAS = 750000 // in case there is a complicated path, reserve more gas
 )
 
-func BuildCallLocalReth(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashbots_client.Bundle, *big.Int, *big.Int, error) {
+// buying more than half of the pool's rETH moves the price far beyond any rETH discount
+const DEFAULT_MAX_POOL_SHARE_PCT = 50
+
+// in ETH, even a large exit at a steep rETH discount stays well below
+const DEFAULT_MAX_PLAUSIBLE_PROFIT = 2.0
+
+func BuildCallLocalReth(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*BuildResult, error) {
 	logger.With(slog.String("function", "BuildCallLocalReth"))
 
 	baseGas, tipGas, err := getCurrentGasSettings(ctx, dataIn.Client, dataIn.Ratelimit)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to get current gas settings"), err)
+		return nil, errors.Join(errors.New("failed to get current gas settings"), err)
 	}
 
 	baseGasBoosted := new(big.Int).Div(new(big.Int).Mul(baseGas, big.NewInt(150)), big.NewInt(100))
+	baseGasBoosted, tipGas = applyTipSettings(dataIn, baseGasBoosted, tipGas)
 
 	if logger.Enabled(ctx, slog.LevelInfo) {
 		baseGasFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(baseGas), new(big.Float).SetInt(big.NewInt(1e9))).Float64()
@@ -55,15 +63,40 @@ func BuildCallLocalReth(ctx context.Context, logger *slog.Logger, dataIn DataIn)
 		fmt.Printf("Sending transaction with a base fee per gas of %.2f gwei for timely inclusion.\n\n", baseGasBoostedFloat)
 	}
 
-	nonce, err := getCurrentNonce(ctx, dataIn.Client, *dataIn.NodeAddress, dataIn.Ratelimit)
+	// the limits of all minipools, the filters below plan with them
+	gasLimits, err := estimateDistributeGas(ctx, logger, dataIn, dataIn.MinipoolAddresses)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to estimate distribute gas"), err)
+	}
+
+	// dataIn is a copy, limiting the minipools here only affects this build
+	included, deferred, err := applyGasBudget(ctx, logger, dataIn, gasLimits)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to apply gas budget"), err)
+	}
+	dataIn.MinipoolAddresses = included
+	if len(dataIn.MinipoolAddresses) == 0 {
+		return nil, ErrNothingToDistribute
+	}
+
+	if dataIn.BeaconUrl != "" && logger.Enabled(ctx, slog.LevelInfo) {
+		warnBeaconBalances(ctx, logger, dataIn, dataIn.MinipoolAddresses)
+	}
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		warnBalanceTrend(ctx, logger, dataIn, dataIn.MinipoolAddresses)
+	}
+
+	nonce, err := getStartNonce(ctx, dataIn)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to get current nonce"), err)
+		return nil, errors.Join(errors.New("failed to get current nonce"), err)
 	}
 
 	txs, err := generateAndBuildDistributeCalls(
+		dataIn.TxType,
 		dataIn.NetworkId,
 		nonce,
 		dataIn.MinipoolAddresses,
+		gasLimits,
 		baseGasBoosted,
 		tipGas,
 		logger,
@@ -71,22 +104,22 @@ func BuildCallLocalReth(ctx context.Context, logger *slog.Logger, dataIn DataIn)
 		dataIn.NodeAddressPrivateKey,
 	)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to generate distribute calls"), err)
+		return nil, errors.Join(errors.New("failed to generate distribute calls"), err)
 	}
 
 	rETHShare, err := CalcaulteDistributedBalance(ctx, logger, dataIn.Client, dataIn.MinipoolAddresses, dataIn.Ratelimit)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to calculate distributed balance"), err)
+		return nil, errors.Join(errors.New("failed to calculate distributed balance"), err)
 	}
 
 	rEthContractAddress, err := GetREthContractAddress(dataIn.NetworkId)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to get rETH contract address"), err)
+		return nil, errors.Join(errors.New("failed to get rETH contract address"), err)
 	}
 
 	rethInstance, err := rETH.NewRETH(rEthContractAddress, dataIn.Client)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to create rETH instance"), err)
+		return nil, errors.Join(errors.New("failed to create rETH instance"), err)
 	}
 
 	// always print the rETH burn overwite message, its recomended to simulate these transactions on tenderly
@@ -97,7 +130,7 @@ func BuildCallLocalReth(ctx context.Context, logger *slog.Logger, dataIn DataIn)
 	// calcaulte amount rETH to burn
 	rethToBurn, err := ConvertWethToReth(ctx, rethInstance, rETHShare)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to convert rETH to WETH"), err)
+		return nil, errors.Join(errors.New("failed to convert rETH to WETH"), err)
 	}
 	if dataIn.Ratelimit > 0 {
 		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
@@ -105,37 +138,59 @@ func BuildCallLocalReth(ctx context.Context, logger *slog.Logger, dataIn DataIn)
 
 	logger.Debug("calculated rETH to burn", slog.String("rethToBurn", rethToBurn.String()))
 
+	exchangeRate, err := GetExchangeRateSource(ctx, dataIn.Client, dataIn.NetworkId, dataIn.Ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get exchange rate source"), err)
+	}
+
 	nextNonce := nonce + uint64(len(txs))
-	rawBurnTx, err := generateBurnCall(rEthContractAddress, dataIn.NetworkId, nextNonce, rethToBurn, baseGasBoosted, tipGas)
+	rawBurnTx, err := generateBurnCall(dataIn.TxType, rEthContractAddress, dataIn.NetworkId, nextNonce, rethToBurn, baseGasBoosted, tipGas)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to generate burn call"), err)
+		return nil, errors.Join(errors.New("failed to generate burn call"), err)
 	}
 
-	signedBurnTx, err := signTransaction(logger, dataIn.Command, dataIn.NodeAddressPrivateKey, rawBurnTx)
+	signedBurnTx, err := signTransaction(logger, dataIn.Command, dataIn.NodeAddressPrivateKey, dataIn.NetworkId, rawBurnTx)
 	if err != nil {
-		return nil, nil, nil, errors.Join(errors.New("failed to sign burn tx"), err)
+		return nil, errors.Join(errors.New("failed to sign burn tx"), err)
 	}
 
 	logger.Debug("signed burn tx", slog.String("txHash", signedBurnTx.Hash().Hex()))
 	txs = append(txs, signedBurnTx)
+	arbitrageTx := signedBurnTx
 
 	if dataIn.Ratelimit > 0 {
 		time.Sleep(time.Duration(dataIn.Ratelimit*4) * time.Millisecond)
 	}
+
+	// advisory reads ignore their errors, a cancelled build must not return a bundle
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Join(errors.New("build cancelled"), err)
+	}
 	bundle := flashbots_client.NewBundleWithTransactions(txs)
 
-	return bundle, rethToBurn, rETHShare, nil
+	return &BuildResult{
+		Bundle:      bundle,
+		ArbitrageTx: arbitrageTx,
+		RethShare:   rETHShare,
+		RethToBurn:  rethToBurn,
+
+		ExchangeRate: exchangeRate,
+
+		IncludedMinipools: dataIn.MinipoolAddresses,
+		DeferredMinipools: deferred,
+	}, nil
 }
 
-func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashbots_client.Bundle, *big.Int, error) {
+func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*BuildResult, error) {
 	logger.With(slog.String("function", "BuildCall"))
 
 	baseGas, tipGas, err := getCurrentGasSettings(ctx, dataIn.Client, dataIn.Ratelimit)
 	if err != nil {
-		return nil, nil, errors.Join(errors.New("failed to get current gas settings"), err)
+		return nil, errors.Join(errors.New("failed to get current gas settings"), err)
 	}
 
 	baseGasBoosted := new(big.Int).Div(new(big.Int).Mul(baseGas, big.NewInt(150)), big.NewInt(100))
+	baseGasBoosted, tipGas = applyTipSettings(dataIn, baseGasBoosted, tipGas)
 
 	if logger.Enabled(ctx, slog.LevelInfo) {
 		baseGasFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(baseGas), new(big.Float).SetInt(big.NewInt(1e9))).Float64()
@@ -146,15 +201,54 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 		fmt.Printf("Sending transaction with a base fee per gas of %.2f gwei for timely inclusion.\n\n", baseGasBoostedFloat)
 	}
 
-	nonce, err := getCurrentNonce(ctx, dataIn.Client, *dataIn.NodeAddress, dataIn.Ratelimit)
+	// the limits of all minipools, the filters below plan with them
+	gasLimits, err := estimateDistributeGas(ctx, logger, dataIn, dataIn.MinipoolAddresses)
 	if err != nil {
-		return nil, nil, errors.Join(errors.New("failed to get current nonce"), err)
+		return nil, errors.Join(errors.New("failed to estimate distribute gas"), err)
+	}
+
+	// dataIn is a copy, limiting the minipools here only affects this build
+	included, deferred, err := applyGasBudget(ctx, logger, dataIn, gasLimits)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to apply gas budget"), err)
+	}
+	dataIn.MinipoolAddresses = included
+
+	included, notTop, err := applyTopMinipools(ctx, logger, dataIn, baseGasBoosted, tipGas, gasLimits)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to rank minipools"), err)
+	}
+	dataIn.MinipoolAddresses = included
+	deferred = append(deferred, notTop...)
+
+	included, belowTier, err := applyCommissionTiers(ctx, logger, dataIn, baseGasBoosted, tipGas, gasLimits)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to apply commission tiers"), err)
+	}
+	dataIn.MinipoolAddresses = included
+	deferred = append(deferred, belowTier...)
+	if len(dataIn.MinipoolAddresses) == 0 {
+		return nil, ErrNothingToDistribute
+	}
+
+	if dataIn.BeaconUrl != "" && logger.Enabled(ctx, slog.LevelInfo) {
+		warnBeaconBalances(ctx, logger, dataIn, dataIn.MinipoolAddresses)
+	}
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		warnBalanceTrend(ctx, logger, dataIn, dataIn.MinipoolAddresses)
+	}
+
+	nonce, err := getStartNonce(ctx, dataIn)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get current nonce"), err)
 	}
 
 	txs, err := generateAndBuildDistributeCalls(
+		dataIn.TxType,
 		dataIn.NetworkId,
 		nonce,
 		dataIn.MinipoolAddresses,
+		gasLimits,
 		baseGasBoosted,
 		tipGas,
 		logger,
@@ -162,7 +256,7 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 		dataIn.NodeAddressPrivateKey,
 	)
 	if err != nil {
-		return nil, nil, errors.Join(errors.New("failed to generate distribute calls"), err)
+		return nil, errors.Join(errors.New("failed to generate distribute calls"), err)
 	}
 
 	uniswapData, paraswapData, err := CalcualteArbitrageData(
@@ -177,10 +271,27 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 		dataIn.Protocol,
 	)
 	if err != nil {
-		return nil, nil, errors.Join(errors.New("failed to calculate arbitrage data"), err)
+		return nil, errors.Join(errors.New("failed to calculate arbitrage data"), err)
 	}
 
-	disributeFee := len(dataIn.MinipoolAddresses) * DISTRIBUTE_CALL_MAX_GAS
+	exchangeRate, err := GetExchangeRateSource(ctx, dataIn.Client, dataIn.NetworkId, dataIn.Ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get exchange rate source"), err)
+	}
+
+	// usd values are informational only, without a price the summary is shown in ETH only
+	var usdPrice *ChainlinkPrice
+	if dataIn.ChainlinkFeed != nil {
+		usdPrice, err = GetChainlinkPrice(ctx, dataIn.Client, *dataIn.ChainlinkFeed, dataIn.Ratelimit)
+		if err != nil {
+			logger.Warn("failed to read chainlink feed, showing ETH only", slog.String("feed", dataIn.ChainlinkFeed.Hex()), slog.String("error", err.Error()))
+			usdPrice = nil
+		} else if age := time.Since(usdPrice.UpdatedAt); age > CHAINLINK_MAX_PRICE_AGE {
+			logger.Warn("chainlink price is stale", slog.String("feed", dataIn.ChainlinkFeed.Hex()), slog.Duration("age", age.Round(time.Second)))
+		}
+	}
+
+	disributeFee := int(totalGas(gasLimits, dataIn.MinipoolAddresses))
 
 	if uniswapData != nil {
 		uniswapData.expectedFee = disributeFee + ARBITRAGE_UNISWAP_CALL_MAX_GAS
@@ -237,14 +348,97 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 
 	arbitrageContractAddress, err := GetArbitrageContractAddress(dataIn.NetworkId)
 	if err != nil {
-		return nil, nil, errors.Join(errors.New("failed to get arbitrage contract address"), err)
+		return nil, errors.Join(errors.New("failed to get arbitrage contract address"), err)
+	}
+
+	useUniswap := dataIn.Protocol == UniswapProtocol || (dataIn.Protocol == BestProtocol && uniswapIsBetter)
+
+	// paraswap does not expose the route, use the main pool as reference
+	impactPool := common.HexToAddress(uniswap.PoolA)
+	if useUniswap {
+		impactPool = uniswapData.poolAddress
+	}
+
+	var impactAmount *big.Int
+	if useUniswap {
+		impactAmount = uniswapData.swapOutAmountReth
+	} else {
+		impactAmount = paraswapData.swapOutAmountReth
+	}
+
+	// the price impact is informational unless --max-price-impact-pct is set, the values stay 0 if it cannot be read
+	var discountBefore, discountAfter float64
+	priceBefore, priceAfter, priceImpact, err := getPriceImpact(ctx, dataIn.Client, impactPool, impactAmount, dataIn.Ratelimit)
+	if err != nil && dataIn.MaxPriceImpactPct > 0 {
+		return nil, errors.Join(errors.New("failed to calculate price impact"), err)
+	}
+	if err != nil {
+		logger.Warn("failed to calculate price impact", slog.String("pool", impactPool.Hex()), slog.String("error", err.Error()))
+		priceBefore, priceAfter, priceImpact = 0, 0, 0
+	} else {
+		logger.Debug("calculated price impact",
+			slog.String("pool", impactPool.Hex()),
+			slog.Float64("priceBefore", priceBefore),
+			slog.Float64("priceAfter", priceAfter),
+			slog.Float64("priceImpactPct", priceImpact),
+		)
+
+		discountBefore = poolDiscount(priceBefore, exchangeRate.Rate)
+		discountAfter = poolDiscount(priceAfter, exchangeRate.Rate)
+		logger.Debug("pool discount", slog.Float64("discountBeforePct", discountBefore), slog.Float64("discountAfterPct", discountAfter))
+
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Printf("Pool price impact: %.5f -> %.5f WETH per rETH (%.4f%%)\n", priceBefore, priceAfter, priceImpact)
+			fmt.Printf("Pool discount to the protocol rate: %.4f%% -> %.4f%%\n\n", discountBefore, discountAfter)
+		}
+	}
+
+	// paraswap may split the route over several pools, only the direct uniswap swap can be checked
+	if useUniswap {
+		err = checkPoolLiquidity(ctx, dataIn, uniswapData.poolAddress, uniswapData.swapOutAmountReth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var swapIn, swapOut *big.Int
+	if useUniswap {
+		swapIn, swapOut = uniswapData.swapInAmountWeth, uniswapData.swapOutAmountReth
+	} else {
+		swapIn, swapOut = paraswapData.swapInAmountWeth, paraswapData.swapOutAmountReth
 	}
+	swapRate, protocolRate, capturedPct := effectiveSwapRate(swapIn, swapOut, exchangeRate.Rate)
 
-	var expectedProfit *big.Int
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Printf("Swap rate: %.6f ETH/rETH vs protocol %.6f, capturing %.4f%%.\n", swapRate, protocolRate, capturedPct)
+		if capturedPct <= 0 {
+			fmt.Print(colorOrange, "Warning: the swap route does not buy rETH below the protocol rate, there is no discount to capture.", colorReset, "\n")
+		}
+		fmt.Println()
+	}
+
+	if dataIn.MaxPriceImpactPct > 0 && priceImpact > dataIn.MaxPriceImpactPct {
+		return nil, fmt.Errorf("price impact of %.4f%% exceeds the maximum of %.4f%%", priceImpact, dataIn.MaxPriceImpactPct)
+	}
+
+	var expectedProfit, rethShare, rethToBurn *big.Int
+	var arbitrageTx *types.Transaction
 	nextNonce := nonce + uint64(len(txs))
 	logger.Debug("signed distribute txs", slog.Int("count", len(txs)))
-	if dataIn.Protocol == UniswapProtocol || (dataIn.Protocol == BestProtocol && uniswapIsBetter) {
+
+	// the arbitrage tx is permissionless, a separate fee payer can send it with its own nonce
+	arbitrageSigner := dataIn.NodeAddressPrivateKey
+	if dataIn.FeePayerPrivateKey != nil {
+		arbitrageSigner = dataIn.FeePayerPrivateKey
+		nextNonce, err = getCurrentNonce(ctx, dataIn.Client, *dataIn.FeePayerAddress, dataIn.Ratelimit)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to get fee payer nonce"), err)
+		}
+	}
+	if useUniswap {
 		expectedProfit = new(big.Int).Sub(uniswapData.expectedProfit, big.NewInt(int64(uniswapData.expectedFee)))
+		rethShare = uniswapData.rethShare
+		rethToBurn = uniswapData.swapOutAmountReth
 
 		var minProfit *big.Int
 		if dataIn.CheckProfit {
@@ -263,6 +457,7 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 		}
 
 		rawArbitrageTx, err := generateArbitrageCall(
+			dataIn.TxType,
 			dataIn.NetworkId,
 			nextNonce,
 			uniswapData,
@@ -273,18 +468,21 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 			*dataIn.ReceiverAddress,
 		)
 		if err != nil {
-			return nil, nil, errors.Join(errors.New("failed to generate arbitrage call"), err)
+			return nil, errors.Join(errors.New("failed to generate arbitrage call"), err)
 		}
 
-		signedArbitrageTx, err := signTransaction(logger, dataIn.Command, dataIn.NodeAddressPrivateKey, rawArbitrageTx)
+		signedArbitrageTx, err := signTransaction(logger, dataIn.Command, arbitrageSigner, dataIn.NetworkId, rawArbitrageTx)
 		if err != nil {
-			return nil, nil, errors.Join(errors.New("failed to sign arbitrage tx"), err)
+			return nil, errors.Join(errors.New("failed to sign arbitrage tx"), err)
 		}
 
 		logger.Debug("signed arbitrage tx", slog.String("txHash", signedArbitrageTx.Hash().Hex()))
 		txs = append(txs, signedArbitrageTx)
-	} else if dataIn.Protocol == ParaswapProtocol || (dataIn.Protocol == BestProtocol && !uniswapIsBetter) {
+		arbitrageTx = signedArbitrageTx
+	} else if dataIn.Protocol == ParaswapProtocol || dataIn.Protocol == BestProtocol {
 		expectedProfit = new(big.Int).Sub(paraswapData.expectedProfit, big.NewInt(int64(paraswapData.expectedFee)))
+		rethShare = paraswapData.rethShare
+		rethToBurn = paraswapData.swapOutAmountReth
 
 		var minProfit *big.Int
 		if dataIn.CheckProfit {
@@ -303,6 +501,7 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 		}
 
 		rawArbitrageTx, err := generateParaswapArbitrageCall(
+			dataIn.TxType,
 			dataIn.NetworkId,
 			nextNonce,
 			paraswapData,
@@ -313,33 +512,181 @@ func BuildCall(ctx context.Context, logger *slog.Logger, dataIn DataIn) (*flashb
 			*dataIn.ReceiverAddress,
 		)
 		if err != nil {
-			return nil, nil, errors.Join(errors.New("failed to generate paraswap call"), err)
+			return nil, errors.Join(errors.New("failed to generate paraswap call"), err)
 		}
 
-		signedParaswapTx, err := signTransaction(logger, dataIn.Command, dataIn.NodeAddressPrivateKey, rawArbitrageTx)
+		signedParaswapTx, err := signTransaction(logger, dataIn.Command, arbitrageSigner, dataIn.NetworkId, rawArbitrageTx)
 		if err != nil {
-			return nil, nil, errors.Join(errors.New("failed to sign paraswap tx"), err)
+			return nil, errors.Join(errors.New("failed to sign paraswap tx"), err)
 		}
 
 		logger.Debug("signed arbitrage tx", slog.String("txHash", signedParaswapTx.Hash().Hex()))
 		txs = append(txs, signedParaswapTx)
+		arbitrageTx = signedParaswapTx
 	} else {
 		fmt.Println("Protocol picked: ", dataIn.Protocol)
 		fmt.Println("Uniswap is better: ", uniswapIsBetter)
-		return nil, nil, errors.New("invalid protocol")
+		return nil, errors.New("invalid protocol")
+	}
+
+	// the donation follows the arbitrage, the profit is only there once it ran
+	var donationTx *types.Transaction
+	if dataIn.DonatePct > 0 {
+		donationTx, err = generateDonationTx(logger, dataIn, txs, expectedProfit, baseGasBoosted, tipGas)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to generate donation tx"), err)
+		}
+		if donationTx != nil {
+			txs = append(txs, donationTx)
+			expectedProfit = new(big.Int).Sub(expectedProfit, donationTx.Value())
+		}
 	}
 
 	if dataIn.Ratelimit > 0 {
 		time.Sleep(time.Duration(dataIn.Ratelimit*4) * time.Millisecond)
 	}
+
+	// advisory reads ignore their errors, a cancelled build must not return a bundle
+	if err := ctx.Err(); err != nil {
+		return nil, errors.Join(errors.New("build cancelled"), err)
+	}
 	bundle := flashbots_client.NewBundleWithTransactions(txs)
 
-	return bundle, expectedProfit, nil
+	return &BuildResult{
+		Bundle:         bundle,
+		ArbitrageTx:    arbitrageTx,
+		DonationTx:     donationTx,
+		ExpectedProfit: expectedProfit,
+		RethShare:      rethShare,
+		RethToBurn:     rethToBurn,
+
+		PoolPriceBefore: priceBefore,
+		PoolPriceAfter:  priceAfter,
+		PriceImpactPct:  priceImpact,
+
+		PoolDiscountBeforePct: discountBefore,
+		PoolDiscountAfterPct:  discountAfter,
+
+		ExchangeRate: exchangeRate,
+		UsdPrice:     usdPrice,
+
+		IncludedMinipools: dataIn.MinipoolAddresses,
+		DeferredMinipools: deferred,
+	}, nil
+}
+
+// checkPoolLiquidity errors if the swap would take more than dataIn.MaxPoolSharePct of the pool's rETH.
+// Such a swap either fails or moves the price so far that the arbitrage is not worth the gas.
+func checkPoolLiquidity(ctx context.Context, dataIn DataIn, pool common.Address, swapOutReth *big.Int) error {
+	if dataIn.MaxPoolSharePct <= 0 {
+		return nil
+	}
+
+	rEthContractAddress, err := GetREthContractAddress(dataIn.NetworkId)
+	if err != nil {
+		return errors.Join(errors.New("failed to get rETH contract address"), err)
+	}
+
+	instance, err := rETH.NewRETH(rEthContractAddress, dataIn.Client)
+	if err != nil {
+		return errors.Join(errors.New("failed to create rETH instance"), err)
+	}
+
+	poolReth, err := instance.BalanceOf(&bind.CallOpts{Context: ctx}, pool)
+	if err != nil {
+		return errors.Join(errors.New("failed to get rETH balance of the pool"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	if poolReth.Sign() == 0 {
+		return fmt.Errorf("pool %s has no rETH liquidity", pool.Hex())
+	}
+
+	sharePct, _ := new(big.Float).Quo(new(big.Float).SetInt(swapOutReth), new(big.Float).SetInt(poolReth)).Float64()
+	sharePct *= 100
+	if sharePct > dataIn.MaxPoolSharePct {
+		return fmt.Errorf("pool too shallow for this size: the swap buys %.4f of the %.4f rETH in pool %s (%.2f%%, max %.2f%%). Distribute fewer minipools at once (e.g. with --bundle-size)",
+			weiToEth(swapOutReth),
+			weiToEth(poolReth),
+			pool.Hex(),
+			sharePct,
+			dataIn.MaxPoolSharePct,
+		)
+	}
+
+	return nil
+}
+
+// effectiveSwapRate returns the ETH paid per rETH by the swap, the protocol rate and the discount captured in percent.
+// The rETH is burned at the protocol rate, so the captured discount is what the arbitrage earns before fees.
+func effectiveSwapRate(swapInWeth, swapOutReth, protocolRate *big.Int) (float64, float64, float64) {
+	if swapInWeth == nil || swapOutReth == nil || protocolRate == nil || swapOutReth.Sign() == 0 || protocolRate.Sign() == 0 {
+		return 0, 0, 0
+	}
+
+	swapRate, _ := new(big.Float).Quo(new(big.Float).SetInt(swapInWeth), new(big.Float).SetInt(swapOutReth)).Float64()
+	rate, _ := new(big.Float).Quo(new(big.Float).SetInt(protocolRate), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+
+	return swapRate, rate, (rate - swapRate) / rate * 100
+}
+
+// poolDiscount returns how far the pool price is below the protocol rate in percent, negative for a premium
+func poolDiscount(poolPrice float64, protocolRate *big.Int) float64 {
+	rate, _ := new(big.Float).Quo(new(big.Float).SetInt(protocolRate), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+	if rate == 0 {
+		return 0
+	}
+
+	return (rate - poolPrice) / rate * 100
+}
+
+// getPriceImpact returns the pool price before and after buying amount rETH and the change in percent
+func getPriceImpact(ctx context.Context, client *ethclient.Client, pool common.Address, amount *big.Int, ratelimit int) (float64, float64, float64, error) {
+	priceBefore, err := uniswap.GetPoolPrice(ctx, client, pool, ratelimit)
+	if err != nil {
+		return 0, 0, 0, errors.Join(errors.New("failed to get pool price"), err)
+	}
+
+	priceAfter, err := uniswap.GetPoolPriceAfterWithdrawArb(ctx, client, pool, amount, ratelimit)
+	if err != nil {
+		return 0, 0, 0, errors.Join(errors.New("failed to get pool price after swap"), err)
+	}
+
+	if priceBefore == 0 {
+		return 0, 0, 0, errors.New("pool price is zero")
+	}
+
+	return priceBefore, priceAfter, (priceAfter - priceBefore) / priceBefore * 100, nil
 }
 
 func CalcaulteDistributedBalance(ctx context.Context, logger *slog.Logger, client *ethclient.Client, minipoolAddresses []common.Address, ratelimit int) (*big.Int, error) {
+	shares, err := CalculateMinipoolShares(ctx, logger, client, minipoolAddresses, ratelimit)
+	if err != nil {
+		return nil, err
+	}
+
 	totalNodeShare := new(big.Int)
 	totalDistributeAmount := new(big.Int)
+	for _, share := range shares {
+		totalDistributeAmount = new(big.Int).Add(totalDistributeAmount, share.RethShare)
+		totalNodeShare = new(big.Int).Add(totalNodeShare, share.NodeShare)
+	}
+
+	totalNodeShareFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(totalNodeShare), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+	rETHShareFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(totalDistributeAmount), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Printf("Calculated distribution amounts: %.6f ETH sent to NO, %.6f ETH sent to rETH contract.\n\n", totalNodeShareFloat, rETHShareFloat)
+	}
+
+	return totalDistributeAmount, nil
+}
+
+// CalculateMinipoolShares returns how the balance of each minipool would be split by a distribute call
+func CalculateMinipoolShares(ctx context.Context, logger *slog.Logger, client *ethclient.Client, minipoolAddresses []common.Address, ratelimit int) ([]MinipoolShare, error) {
+	shares := make([]MinipoolShare, 0, len(minipoolAddresses))
 	for _, minipoolAddress := range minipoolAddresses {
 		balance, err := client.BalanceAt(ctx, minipoolAddress, nil)
 		if err != nil {
@@ -380,20 +727,15 @@ func CalcaulteDistributedBalance(ctx context.Context, logger *slog.Logger, clien
 			time.Sleep(time.Duration(ratelimit) * time.Millisecond)
 		}
 
-		nodeShare := new(big.Int).Sub(balance, rETHShare)
-
-		totalDistributeAmount = new(big.Int).Add(totalDistributeAmount, rETHShare)
-		totalNodeShare = new(big.Int).Add(totalNodeShare, nodeShare)
+		shares = append(shares, MinipoolShare{
+			Address:   minipoolAddress,
+			Balance:   balance,
+			RethShare: rETHShare,
+			NodeShare: new(big.Int).Sub(balance, rETHShare),
+		})
 	}
 
-	totalNodeShareFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(totalNodeShare), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
-	rETHShareFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(totalDistributeAmount), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
-
-	if logger.Enabled(ctx, slog.LevelInfo) {
-		fmt.Printf("Calculated distribution amounts: %.6f ETH sent to NO, %.6f ETH sent to rETH contract.\n\n", totalNodeShareFloat, rETHShareFloat)
-	}
-
-	return totalDistributeAmount, nil
+	return shares, nil
 }
 
 func CalcualteArbitrageData(
@@ -449,6 +791,7 @@ func CalcualteArbitrageData(
 		return nil, nil, errors.Join(errors.New("failed to fetch paraswap data"), err)
 	}
 
+	dataParaswap.rethShare = rETHShare
 	dataParaswap.expectedProfit = new(big.Int).Sub(rETHShare, dataParaswap.swapInAmountWeth)
 
 	primaryRatio := new(big.Float).Quo(new(big.Float).SetInt(rETHShare), new(big.Float).SetInt(rethToBurn))
@@ -483,6 +826,7 @@ func CalcualteArbitrageData(
 	uniswapReturnAmountWeth = new(big.Int).Sub(uniswapReturnAmountWeth, big.NewInt(5))
 	dataUniswap := &UniswapArbitrage{
 		poolAddress:       poolAddress,
+		rethShare:         rETHShare,
 		swapInAmountWeth:  uniswapReturnAmountWeth,
 		swapOutAmountReth: rethToBurn,
 		sqrtPriceLimitX96: sqrtPriceLimitX96,
@@ -525,6 +869,20 @@ func getCurrentGasSettings(ctx context.Context, client *ethclient.Client, rateli
 	return baseGas, tipGas, eg.Wait()
 }
 
+// getStartNonce returns the nonce of the first bundle tx: the explicitly set nonce, or the pending nonce plus the offset
+func getStartNonce(ctx context.Context, dataIn DataIn) (uint64, error) {
+	if dataIn.Nonce != nil {
+		return *dataIn.Nonce, nil
+	}
+
+	nonce, err := getCurrentNonce(ctx, dataIn.Client, *dataIn.NodeAddress, dataIn.Ratelimit)
+	if err != nil {
+		return 0, err
+	}
+
+	return nonce + dataIn.NonceOffset, nil
+}
+
 func getCurrentNonce(ctx context.Context, client *ethclient.Client, address common.Address, ratelimit int) (uint64, error) {
 	nonce, err := client.PendingNonceAt(ctx, address)
 	if err != nil {
@@ -539,8 +897,10 @@ func getCurrentNonce(ctx context.Context, client *ethclient.Client, address comm
 }
 
 func generateAndBuildDistributeCalls(
+	txType TxType,
 	networkId, nonce uint64,
 	minipoolAddresses []common.Address,
+	gasLimits map[common.Address]uint64,
 	baseGas, tipGas *big.Int,
 	logger *slog.Logger,
 	apiCommand string,
@@ -549,12 +909,12 @@ func generateAndBuildDistributeCalls(
 	var txs []*types.Transaction
 
 	for i, minipoolAddress := range minipoolAddresses {
-		rawTx, err := generateDistributeCall(networkId, nonce+uint64(i), minipoolAddress, baseGas, tipGas)
+		rawTx, err := generateDistributeCall(txType, networkId, nonce+uint64(i), minipoolAddress, gasLimits[minipoolAddress], baseGas, tipGas)
 		if err != nil {
 			return nil, errors.Join(errors.New("failed to generate distribute call"), err)
 		}
 
-		signedTx, err := signTransaction(logger, apiCommand, privateKey, rawTx)
+		signedTx, err := signTransaction(logger, apiCommand, privateKey, networkId, rawTx)
 		if err != nil {
 			return nil, errors.Join(errors.New("failed to sign distribute tx"), err)
 		}
@@ -565,7 +925,7 @@ func generateAndBuildDistributeCalls(
 	return txs, nil
 }
 
-func generateDistributeCall(chainId, nonce uint64, minipoolAddress common.Address, baseGas, tipGas *big.Int) (*types.Transaction, error) {
+func generateDistributeCall(txType TxType, chainId, nonce uint64, minipoolAddress common.Address, gasLimit uint64, baseGas, tipGas *big.Int) (*types.Transaction, error) {
 	minipoolAbi, err := abi.JSON(strings.NewReader(minipoolDelegate.MinipoolDelegateABI))
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to get minipool ABI"), err)
@@ -584,14 +944,14 @@ func generateDistributeCall(chainId, nonce uint64, minipoolAddress common.Addres
 		GasTipCap: tipGas,
 		To:        &minipoolAddress,
 		Value:     big.NewInt(0),
-		Gas:       DISTRIBUTE_CALL_MAX_GAS,
+		Gas:       gasLimit,
 		Data:      callData,
 	}
 
-	return types.NewTx(dynTx), nil
+	return newTx(txType, dynTx), nil
 }
 
-func generateArbitrageCall(chainId, nonce uint64, uniswapData *UniswapArbitrage, minProfit, baseGas, tipGas *big.Int, arbitrageContractAddress, receiver common.Address) (*types.Transaction, error) {
+func generateArbitrageCall(txType TxType, chainId, nonce uint64, uniswapData *UniswapArbitrage, minProfit, baseGas, tipGas *big.Int, arbitrageContractAddress, receiver common.Address) (*types.Transaction, error) {
 	arbitrageAbi, err := abi.JSON(strings.NewReader(contract.ContractABI))
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to get arbitrage ABI"), err)
@@ -620,10 +980,11 @@ func generateArbitrageCall(chainId, nonce uint64, uniswapData *UniswapArbitrage,
 		Data:      callData,
 	}
 
-	return types.NewTx(dynTx), nil
+	return newTx(txType, dynTx), nil
 }
 
 func generateParaswapArbitrageCall(
+	txType TxType,
 	chainId, nonce uint64,
 	paraswapData *ParaswapArbitrage,
 	minProfit, baseGas, tipGas *big.Int,
@@ -657,10 +1018,10 @@ func generateParaswapArbitrageCall(
 		Data:      callData,
 	}
 
-	return types.NewTx(dynTx), nil
+	return newTx(txType, dynTx), nil
 }
 
-func generateBurnCall(rethContractAddress common.Address, chainId, nonce uint64, rethToBurn, baseGas, tipGas *big.Int) (*types.Transaction, error) {
+func generateBurnCall(txType TxType, rethContractAddress common.Address, chainId, nonce uint64, rethToBurn, baseGas, tipGas *big.Int) (*types.Transaction, error) {
 	rEthAbi, err := abi.JSON(strings.NewReader(rETH.RETHABI))
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to get arbitrage ABI"), err)
@@ -682,12 +1043,30 @@ func generateBurnCall(rethContractAddress common.Address, chainId, nonce uint64,
 		Data:      callData,
 	}
 
-	return types.NewTx(dynTx), nil
+	return newTx(txType, dynTx), nil
+}
+
+// newTx wraps the fee settings into the requested transaction type. A legacy transaction
+// has no separate tip, it always pays the fee cap as gas price.
+func newTx(txType TxType, dynTx *types.DynamicFeeTx) *types.Transaction {
+	if txType != TxTypeLegacy {
+		return types.NewTx(dynTx)
+	}
+
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    dynTx.Nonce,
+		GasPrice: dynTx.GasFeeCap,
+		Gas:      dynTx.Gas,
+		To:       dynTx.To,
+		Value:    dynTx.Value,
+		Data:     dynTx.Data,
+	})
 }
 
-func signTransaction(logger *slog.Logger, apiCommand string, privateKey *ecdsa.PrivateKey, tx *types.Transaction) (signedTx *types.Transaction, err error) {
+// signTransaction signs for the given chain, an unsigned legacy tx carries no chain id of its own
+func signTransaction(logger *slog.Logger, apiCommand string, privateKey *ecdsa.PrivateKey, chainId uint64, tx *types.Transaction) (signedTx *types.Transaction, err error) {
 	if privateKey != nil {
-		signedTx, err = types.SignTx(tx, types.LatestSignerForChainID(tx.ChainId()), privateKey)
+		signedTx, err = types.SignTx(tx, types.LatestSignerForChainID(new(big.Int).SetUint64(chainId)), privateKey)
 		if err != nil {
 			return nil, errors.Join(errors.New("failed to sign tx using private key"), err)
 		}
@@ -778,7 +1157,7 @@ func fetchParaswapData(
 		arbitrageContractAddress.Hex(),
 	)
 
-	reqPrices, err := http.NewRequest("GET", urlPrices, nil)
+	reqPrices, err := http.NewRequestWithContext(ctx, "GET", urlPrices, nil)
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to create request"), err)
 	}
@@ -870,7 +1249,7 @@ func fetchParaswapData(
 		return nil, errors.Join(errors.New("failed to marshal transaction body"), err)
 	}
 
-	reqTransaction, err := http.NewRequest("POST", urlTransaction, bytes.NewBuffer(transactionsBodyJSON))
+	reqTransaction, err := http.NewRequestWithContext(ctx, "POST", urlTransaction, bytes.NewBuffer(transactionsBodyJSON))
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to create request"), err)
 	}
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 func Test_fetchParaswapData(t *testing.T) {
@@ -69,3 +71,34 @@ func Test_fetchParaswapData(t *testing.T) {
 		})
 	}
 }
+
+func Test_signTransactionLegacy(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	minipool := common.HexToAddress("0xfA82e08c42E6F62f95623F9ee8f2b15716F02aA6")
+
+	for _, txType := range []TxType{TxTypeLegacy, TxType1559} {
+		rawTx, err := generateDistributeCall(txType, 1, 7, minipool, DISTRIBUTE_CALL_MAX_GAS, big.NewInt(10e9), big.NewInt(1e9))
+		if err != nil {
+			t.Fatalf("%s: generateDistributeCall() error = %v", txType, err)
+		}
+
+		signedTx, err := signTransaction(slog.Default(), "", privateKey, 1, rawTx)
+		if err != nil {
+			t.Fatalf("%s: signTransaction() error = %v", txType, err)
+		}
+		if signedTx.ChainId().Uint64() != 1 {
+			t.Errorf("%s: chain id = %v, want 1", txType, signedTx.ChainId())
+		}
+
+		sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1)), signedTx)
+		if err != nil {
+			t.Fatalf("%s: types.Sender() error = %v", txType, err)
+		}
+		if want := crypto.PubkeyToAddress(privateKey.PublicKey); sender != want {
+			t.Errorf("%s: sender = %s, want %s", txType, sender.Hex(), want.Hex())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BuilderInfo identifies the builder of the block that included the bundle
+type BuilderInfo struct {
+	Block        uint64
+	Name         string // from the block extra data, builders put their name there
+	FeeRecipient common.Address
+}
+
+// getBuilderInfo looks up which builder included the tx. The relay does not report which of the
+// targeted builders won, so this is taken from the block itself.
+func getBuilderInfo(ctx context.Context, dataIn *DataIn, txHash common.Hash) (*BuilderInfo, error) {
+	receipt, err := dataIn.Client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get tx receipt"), err)
+	}
+
+	header, err := dataIn.Client.HeaderByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get block header"), err)
+	}
+
+	return &BuilderInfo{
+		Block:        header.Number.Uint64(),
+		Name:         builderName(header.Extra),
+		FeeRecipient: header.Coinbase,
+	}, nil
+}
+
+// builderName keeps the printable part of the extra data
+func builderName(extra []byte) string {
+	name := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, string(extra))
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
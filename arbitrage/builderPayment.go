@@ -0,0 +1,81 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+)
+
+// builderPayment returns what the builder earns from the bundle at the given base fee: the priority fee of each tx,
+// capped by its fee cap, times the gas used in the simulation. Direct coinbase transfers are added if the relay
+// reported them. Builders order bundles by this value per gas.
+func builderPayment(bundle *flashbots_client.Bundle, simulatedGas []uint64, baseFee *big.Int, res *flashbots_client.SimulationResultBundle) (priorityFees, coinbase *big.Int, gas uint64) {
+	priorityFees = new(big.Int)
+	for index, tx := range bundle.Transactions() {
+		txGas := tx.Gas()
+		if index < len(simulatedGas) {
+			txGas = simulatedGas[index]
+		}
+		gas += txGas
+
+		tip := new(big.Int).Sub(tx.GasFeeCap(), baseFee)
+		if tip.Cmp(tx.GasTipCap()) > 0 {
+			tip = tx.GasTipCap()
+		}
+		if tip.Sign() < 0 {
+			tip = new(big.Int)
+		}
+		priorityFees.Add(priorityFees, new(big.Int).Mul(tip, new(big.Int).SetUint64(txGas)))
+	}
+
+	coinbase = new(big.Int)
+	if res != nil && res.EthSentToCoinbase != nil {
+		coinbase.Set(res.EthSentToCoinbase)
+	}
+
+	return priorityFees, coinbase, gas
+}
+
+// getNextBaseFee returns the base fee of the next block, the last entry of the fee history
+func getNextBaseFee(ctx context.Context, dataIn *DataIn) (*big.Int, error) {
+	history, err := dataIn.Client.FeeHistory(ctx, 1, nil, nil)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get fee history"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	if len(history.BaseFee) == 0 {
+		return nil, errors.New("fee history has no base fee")
+	}
+
+	return history.BaseFee[len(history.BaseFee)-1], nil
+}
+
+// printBuilderPayment prints what the builder earns at the base fee of the next block, nothing if it is unknown
+func printBuilderPayment(dataIn *DataIn, bundle *flashbots_client.Bundle, simulatedGas []uint64, baseFee *big.Int, res *flashbots_client.SimulationResultBundle) {
+	if baseFee == nil {
+		return
+	}
+
+	priorityFees, coinbase, gas := builderPayment(bundle, simulatedGas, baseFee, res)
+	total := new(big.Int).Add(priorityFees, coinbase)
+	perGas := 0.0
+	if gas > 0 {
+		perGas = weiToGwei(total) / float64(gas)
+	}
+
+	fmt.Printf("    Builder payment: %s (%.0f Gwei), priority fees %s and coinbase transfers %s, %.4f Gwei per gas at a base fee of %.2f Gwei\n",
+		formatEth(weiToEth(total), dataIn.Precision, dataIn.Scientific),
+		weiToGwei(total),
+		formatEth(weiToEth(priorityFees), dataIn.Precision, dataIn.Scientific),
+		formatEth(weiToEth(coinbase), dataIn.Precision, dataIn.Scientific),
+		perGas,
+		weiToGwei(baseFee),
+	)
+}
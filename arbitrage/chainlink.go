@@ -0,0 +1,96 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Chainlink ETH/USD feed on mainnet
+const CHAINLINK_ETH_USD_FEED_MAINNET = "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+
+// the ETH/USD feed updates at least once per hour, older answers are only used with a warning
+const CHAINLINK_MAX_PRICE_AGE = 2 * time.Hour
+
+// minimal Chainlink aggregator ABI, only the view functions needed to read the latest price
+const ChainlinkAggregatorABI = `[{"inputs":[],"name":"decimals","outputs":[{"internalType":"uint8","name":"","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"latestRoundData","outputs":[{"internalType":"uint80","name":"roundId","type":"uint80"},{"internalType":"int256","name":"answer","type":"int256"},{"internalType":"uint256","name":"startedAt","type":"uint256"},{"internalType":"uint256","name":"updatedAt","type":"uint256"},{"internalType":"uint80","name":"answeredInRound","type":"uint80"}],"stateMutability":"view","type":"function"}]`
+
+// ChainlinkPrice is the latest answer of a Chainlink feed
+type ChainlinkPrice struct {
+	Feed      common.Address
+	Price     float64 // USD per ETH
+	UpdatedAt time.Time
+}
+
+// GetChainlinkPrice reads the latest answer of a Chainlink price feed and scales it by the feed decimals
+func GetChainlinkPrice(ctx context.Context, client *ethclient.Client, feed common.Address, ratelimit int) (*ChainlinkPrice, error) {
+	feedABI, err := abi.JSON(strings.NewReader(ChainlinkAggregatorABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get chainlink ABI"), err)
+	}
+
+	decimalsValues, err := callChainlinkFeed(ctx, client, feedABI, feed, ratelimit, "decimals")
+	if err != nil {
+		return nil, err
+	}
+	decimals, ok := decimalsValues[0].(uint8)
+	if !ok {
+		return nil, errors.New("unexpected decimals output")
+	}
+
+	roundValues, err := callChainlinkFeed(ctx, client, feedABI, feed, ratelimit, "latestRoundData")
+	if err != nil {
+		return nil, err
+	}
+	answer, ok := roundValues[1].(*big.Int)
+	if !ok {
+		return nil, errors.New("unexpected latestRoundData output")
+	}
+	updatedAt, ok := roundValues[3].(*big.Int)
+	if !ok {
+		return nil, errors.New("unexpected latestRoundData output")
+	}
+
+	if answer.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid chainlink answer %s", answer.String())
+	}
+
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	price, _ := new(big.Float).Quo(new(big.Float).SetInt(answer), scale).Float64()
+
+	return &ChainlinkPrice{
+		Feed:      feed,
+		Price:     price,
+		UpdatedAt: time.Unix(updatedAt.Int64(), 0),
+	}, nil
+}
+
+func callChainlinkFeed(ctx context.Context, client *ethclient.Client, feedABI abi.ABI, feed common.Address, ratelimit int, method string) ([]interface{}, error) {
+	callData, err := feedABI.Pack(method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %v", method, err)
+	}
+
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &feed, Data: callData}, nil)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to call %s", method), err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+
+	values, err := feedABI.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %v", method, err)
+	}
+
+	return values, nil
+}
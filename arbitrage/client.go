@@ -0,0 +1,256 @@
+package arbitrage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DialClient connects to the given eth1 RPC endpoint. If rateLimit is set (requests per second),
+// every outbound request of the returned client is throttled through a shared token bucket.
+func DialClient(ctx context.Context, url string, rateLimit float64) (*ethclient.Client, error) {
+	if rateLimit <= 0 {
+		return ethclient.DialContext(ctx, url)
+	}
+
+	// the throttling is done on the http transport, other transports would bypass it
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, errors.New("rpc rate limit is only supported for http(s) endpoints")
+	}
+
+	httpClient := &http.Client{
+		Transport: &rateLimitedTransport{
+			base:   http.DefaultTransport,
+			bucket: newTokenBucket(rateLimit),
+		},
+	}
+
+	rpcClient, err := rpc.DialOptions(ctx, url, rpc.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	return ethclient.NewClient(rpcClient), nil
+}
+
+// failed endpoints are skipped for this long, unless all endpoints failed
+const RPC_FAILOVER_COOLDOWN = 30 * time.Second
+
+// DialFailoverClient connects to the first of the given http(s) eth1 RPC endpoints. If a call fails with a connection
+// error or a server error, the endpoint is marked unhealthy and the call is retried on the next one.
+func DialFailoverClient(ctx context.Context, logger *slog.Logger, urls []string, rateLimit float64) (*ethclient.Client, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no rpc endpoint given")
+	}
+
+	transport := &failoverTransport{
+		base:      http.DefaultTransport,
+		logger:    logger,
+		unhealthy: make([]time.Time, len(urls)),
+	}
+	if rateLimit > 0 {
+		// one bucket for all endpoints, the limit is meant to protect the node operator's quota per run
+		transport.base = &rateLimitedTransport{
+			base:   http.DefaultTransport,
+			bucket: newTokenBucket(rateLimit),
+		}
+	}
+
+	for _, rawUrl := range urls {
+		if !strings.HasPrefix(rawUrl, "http://") && !strings.HasPrefix(rawUrl, "https://") {
+			return nil, fmt.Errorf("rpc failover is only supported for http(s) endpoints: %s", rawUrl)
+		}
+
+		endpoint, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid rpc url %s", rawUrl), err)
+		}
+		transport.endpoints = append(transport.endpoints, endpoint)
+	}
+
+	rpcClient, err := rpc.DialOptions(ctx, urls[0], rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return nil, err
+	}
+
+	return ethclient.NewClient(rpcClient), nil
+}
+
+type failoverTransport struct {
+	base      http.RoundTripper
+	logger    *slog.Logger
+	endpoints []*url.URL
+
+	mu        sync.Mutex
+	unhealthy []time.Time // zero if healthy, otherwise when the endpoint last failed
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body is sent again on every endpoint that is tried
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, index := range t.order(time.Now()) {
+		endpoint := t.endpoints[index]
+
+		attempt := req.Clone(req.Context())
+		attempt.URL = endpoint
+		attempt.Host = ""
+		attempt.Body = io.NopCloser(bytes.NewReader(body))
+		attempt.ContentLength = int64(len(body))
+		if endpoint.User != nil {
+			password, _ := endpoint.User.Password()
+			attempt.SetBasicAuth(endpoint.User.Username(), password)
+		}
+
+		res, err := t.base.RoundTrip(attempt)
+		if err == nil && res.StatusCode < http.StatusInternalServerError {
+			t.markHealthy(index)
+			t.logger.Debug("rpc call served", slog.String("endpoint", endpoint.Host))
+			return res, nil
+		}
+
+		// a cancelled call would fail on every endpoint
+		if req.Context().Err() != nil {
+			if err == nil {
+				return res, nil
+			}
+			return nil, err
+		}
+
+		if err == nil {
+			err = fmt.Errorf("server error: %s", res.Status)
+			res.Body.Close()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint.Host, err))
+		t.markUnhealthy(index)
+		t.logger.Warn("rpc endpoint failed, failing over",
+			slog.String("endpoint", endpoint.Host),
+			slog.String("error", err.Error()),
+			slog.Any("unhealthy", t.unhealthyHosts(time.Now())),
+		)
+	}
+
+	return nil, errors.Join(append([]error{errors.New("all rpc endpoints failed")}, errs...)...)
+}
+
+// order returns the endpoints to try, healthy ones first in the configured order
+func (t *failoverTransport) order(now time.Time) []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	healthy := []int{}
+	unhealthy := []int{}
+	for index, failedAt := range t.unhealthy {
+		if failedAt.IsZero() || now.Sub(failedAt) > RPC_FAILOVER_COOLDOWN {
+			healthy = append(healthy, index)
+		} else {
+			unhealthy = append(unhealthy, index)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (t *failoverTransport) markHealthy(index int) {
+	t.mu.Lock()
+	t.unhealthy[index] = time.Time{}
+	t.mu.Unlock()
+}
+
+func (t *failoverTransport) markUnhealthy(index int) {
+	t.mu.Lock()
+	t.unhealthy[index] = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *failoverTransport) unhealthyHosts(now time.Time) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hosts := []string{}
+	for index, failedAt := range t.unhealthy {
+		if !failedAt.IsZero() && now.Sub(failedAt) <= RPC_FAILOVER_COOLDOWN {
+			hosts = append(hosts, t.endpoints[index].Host)
+		}
+	}
+
+	return hosts
+}
+
+type rateLimitedTransport struct {
+	base   http.RoundTripper
+	bucket *tokenBucket
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.bucket.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // tokens added per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	// allow at most one second worth of requests in a burst
+	capacity := math.Max(1, math.Floor(rate))
+	return &tokenBucket{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or the context is done
+func (tb *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		tb.mu.Lock()
+		now := time.Now()
+		tb.tokens = math.Min(tb.capacity, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+		tb.last = now
+
+		if tb.tokens >= 1 {
+			tb.tokens--
+			tb.mu.Unlock()
+			return nil
+		}
+
+		wait := time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+		tb.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
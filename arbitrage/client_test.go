@@ -0,0 +1,54 @@
+package arbitrage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_DialFailoverClient(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	served := 0
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		body, _ := io.ReadAll(r.Body)
+		if len(body) == 0 {
+			t.Error("failed over call has no body")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer up.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client, err := DialFailoverClient(context.Background(), logger, []string{down.URL, up.URL}, 0)
+	if err != nil {
+		t.Fatalf("DialFailoverClient() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		chainId, err := client.ChainID(context.Background())
+		if err != nil {
+			t.Fatalf("ChainID() error = %v", err)
+		}
+		if chainId.Uint64() != 1 {
+			t.Errorf("ChainID() = %d, want 1", chainId)
+		}
+	}
+
+	if served != 2 {
+		t.Errorf("healthy endpoint served %d calls, want 2", served)
+	}
+
+	_, err = DialFailoverClient(context.Background(), logger, []string{up.URL, "ws://localhost:8546"}, 0)
+	if err == nil {
+		t.Error("DialFailoverClient() accepted a websocket url")
+	}
+}
@@ -0,0 +1,153 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CommissionTier is the minimum estimated net profit of a minipool whose commission is at least Commission
+type CommissionTier struct {
+	Commission float64 // fraction, e.g. 0.14 for 14%
+	MinProfit  float64 // ETH
+}
+
+// CommissionTiers are sorted by commission
+type CommissionTiers []CommissionTier
+
+// ParseCommissionTiers parses "commission%=minProfitEth" pairs, e.g. "5=0.01,14=0.002"
+func ParseCommissionTiers(value string) (CommissionTiers, error) {
+	tiers := CommissionTiers{}
+	for _, pair := range strings.Split(value, ",") {
+		commissionStr, profitStr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid commission tier %q, expected commission%%=minProfit", pair)
+		}
+
+		commission, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(commissionStr), "%"), 64)
+		if err != nil || commission < 0 || commission > 100 {
+			return nil, fmt.Errorf("invalid commission %q in tier %q, expected a percentage between 0 and 100", commissionStr, pair)
+		}
+
+		minProfit, err := strconv.ParseFloat(strings.TrimSpace(profitStr), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min profit %q in tier %q", profitStr, pair)
+		}
+
+		for _, tier := range tiers {
+			if tier.Commission == commission/100 {
+				return nil, fmt.Errorf("commission %s%% is set twice", commissionStr)
+			}
+		}
+		tiers = append(tiers, CommissionTier{Commission: commission / 100, MinProfit: minProfit})
+	}
+
+	sort.Slice(tiers, func(a, b int) bool {
+		return tiers[a].Commission < tiers[b].Commission
+	})
+
+	return tiers, nil
+}
+
+// tierFor returns the tier with the highest commission not above the given one, false if it is below all tiers
+func (tiers CommissionTiers) tierFor(commission float64) (CommissionTier, bool) {
+	var found CommissionTier
+	ok := false
+	for _, tier := range tiers {
+		if tier.Commission > commission {
+			break
+		}
+		found, ok = tier, true
+	}
+	return found, ok
+}
+
+// applyCommissionTiers keeps the minipools whose estimated net profit reaches the threshold of their commission tier.
+// The estimate is the same as for --top, the rETH share times the pool discount minus the distribute fee at its
+// gas limit. A minipool below the lowest tier is kept. The decision of each minipool is printed.
+func applyCommissionTiers(ctx context.Context, logger *slog.Logger, dataIn DataIn, baseGas, tipGas *big.Int, gasLimits map[common.Address]uint64) (included, deferred []common.Address, err error) {
+	if len(dataIn.CommissionTiers) == 0 {
+		return dataIn.MinipoolAddresses, nil, nil
+	}
+
+	shares, err := CalculateMinipoolShares(ctx, logger, dataIn.Client, dataIn.MinipoolAddresses, dataIn.Ratelimit)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("failed to calculate minipool shares"), err)
+	}
+
+	discount, err := estimatePoolDiscount(ctx, dataIn)
+	if err != nil {
+		return nil, nil, err
+	}
+	scores := scoreMinipools(shares, discount, new(big.Int).Add(baseGas, tipGas), gasLimits)
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Printf("Minipool profit thresholds by commission tier (discount %.4f%%):\n", discount*100)
+		fmt.Printf("    %-42s %10s %8s %12s %12s  %s\n", "Minipool", "Commission", "Tier", "Net", "Threshold", "Decision")
+	}
+
+	// scores are sorted by profit, keep the input order like the other filters
+	kept := map[common.Address]bool{}
+	for _, score := range scores {
+		minipoolInstance, err := minipoolDelegate.NewMinipoolDelegate(score.Address, dataIn.Client)
+		if err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("%s: failed to create minipool instance", score.Address.Hex()), err)
+		}
+
+		nodeFee, err := GetMinipoolNodeFee(ctx, minipoolInstance)
+		if err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("%s: failed to get minipool commission", score.Address.Hex()), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		commission := weiToEth(nodeFee)
+		net := score.ExpectedProfit - score.DistributeFee
+		tier, ok := dataIn.CommissionTiers.tierFor(commission)
+		kept[score.Address] = !ok || net >= tier.MinProfit
+
+		logger.Debug("commission tier",
+			slog.String("minipool", score.Address.Hex()),
+			slog.Float64("commission", commission),
+			slog.Bool("tier", ok),
+			slog.Float64("net", net),
+			slog.Bool("included", kept[score.Address]),
+		)
+
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			tierStr, thresholdStr := "-", "-"
+			if ok {
+				tierStr = strconv.FormatFloat(tier.Commission*100, 'f', -1, 64) + "%"
+				thresholdStr = strconv.FormatFloat(tier.MinProfit, 'f', 6, 64)
+			}
+			decision := "included"
+			if !kept[score.Address] {
+				decision = "deferred"
+			}
+			fmt.Printf("    %-42s %9.2f%% %8s %12.6f %12s  %s\n", score.Address.Hex(), commission*100, tierStr, net, thresholdStr, decision)
+		}
+	}
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Println()
+	}
+
+	for _, minipool := range dataIn.MinipoolAddresses {
+		if kept[minipool] {
+			included = append(included, minipool)
+		} else {
+			deferred = append(deferred, minipool)
+		}
+	}
+
+	return included, deferred, nil
+}
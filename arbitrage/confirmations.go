@@ -0,0 +1,63 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const CONFIRMATION_POLL_INTERVAL = 6 * time.Second
+
+// waitForConfirmations polls until the tx is confirmations blocks deep, counting the including block as the first.
+// Returns the block the tx is in at that point. A tx that disappears after it was seen counts as reorged.
+func waitForConfirmations(ctx context.Context, dataIn *DataIn, txHash common.Hash, confirmations uint64, timeout time.Duration) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	seen := false
+	for {
+		receipt, err := dataIn.Client.TransactionReceipt(ctx, txHash)
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			if seen {
+				return 0, fmt.Errorf("tx %s was removed from the chain, likely by a reorg", txHash.Hex())
+			}
+		case err != nil:
+			if ctx.Err() != nil {
+				return 0, fmt.Errorf("tx %s did not reach %d confirmations within %s", txHash.Hex(), confirmations, timeout)
+			}
+			return 0, errors.Join(errors.New("failed to get tx receipt"), err)
+		default:
+			seen = true
+
+			head, err := dataIn.Client.BlockNumber(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return 0, fmt.Errorf("tx %s did not reach %d confirmations within %s", txHash.Hex(), confirmations, timeout)
+				}
+				return 0, errors.Join(errors.New("failed to get block number"), err)
+			}
+			if dataIn.Ratelimit > 0 {
+				time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+			}
+
+			block := receipt.BlockNumber.Uint64()
+			if head >= block && head-block+1 >= confirmations {
+				return block, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("tx %s did not reach %d confirmations within %s", txHash.Hex(), confirmations, timeout)
+		case <-time.After(CONFIRMATION_POLL_INTERVAL):
+		}
+	}
+}
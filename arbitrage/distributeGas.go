@@ -0,0 +1,144 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	DEFAULT_GAS_BUFFER_PCT = 20
+	// an estimate this much above the median of the batch is reported as a possible anomaly
+	DISTRIBUTE_GAS_ANOMALY_FACTOR = 1.5
+	// above this share of the reserved gas the bundle is close to running out of gas
+	GAS_UTILIZATION_WARN = 0.9
+)
+
+// estimateDistributeGas estimates the gas of each distribute call with eth_estimateGas and adds the buffer.
+// If the estimate fails, the minipool falls back to DISTRIBUTE_CALL_MAX_GAS. Minipools whose estimate is far
+// above the others or above DISTRIBUTE_CALL_MAX_GAS are reported, their state may be unusual.
+func estimateDistributeGas(ctx context.Context, logger *slog.Logger, dataIn DataIn, minipools []common.Address) (map[common.Address]uint64, error) {
+	estimates, failed, err := estimateDistributeCalls(ctx, logger, dataIn, minipools)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, minipoolAddress := range minipools {
+		if estimateErr, ok := failed[minipoolAddress]; ok {
+			logger.Warn("failed to estimate distribute gas, using the default limit",
+				slog.String("minipool", minipoolAddress.Hex()),
+				slog.Uint64("gasLimit", DISTRIBUTE_CALL_MAX_GAS),
+				slog.String("error", estimateErr.Error()),
+			)
+		}
+	}
+	warnGasAnomalies(ctx, logger, estimates)
+
+	return distributeGasLimits(dataIn, minipools, estimates), nil
+}
+
+// estimateDistributeCalls returns the raw estimate of each minipool and the error of each failed one
+func estimateDistributeCalls(ctx context.Context, logger *slog.Logger, dataIn DataIn, minipools []common.Address) (map[common.Address]uint64, map[common.Address]error, error) {
+	minipoolAbi, err := abi.JSON(strings.NewReader(minipoolDelegate.MinipoolDelegateABI))
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("failed to get minipool ABI"), err)
+	}
+
+	callData, err := minipoolAbi.Pack("distributeBalance", false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pack function data: %v", err)
+	}
+
+	estimates := map[common.Address]uint64{}
+	failed := map[common.Address]error{}
+	for _, minipoolAddress := range minipools {
+		msg := ethereum.CallMsg{
+			From: *dataIn.NodeAddress,
+			To:   &minipoolAddress,
+			Data: callData,
+		}
+
+		estimate, err := dataIn.Client.EstimateGas(ctx, msg)
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		// the fallback is only meant for failing estimates, not for a cancelled build
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if err != nil {
+			failed[minipoolAddress] = err
+			continue
+		}
+
+		logger.Debug("estimated distribute gas", slog.String("minipool", minipoolAddress.Hex()), slog.Uint64("gas", estimate))
+		estimates[minipoolAddress] = estimate
+	}
+
+	return estimates, failed, nil
+}
+
+// distributeGasLimits adds the buffer to the estimates, minipools without an estimate get DISTRIBUTE_CALL_MAX_GAS
+func distributeGasLimits(dataIn DataIn, minipools []common.Address, estimates map[common.Address]uint64) map[common.Address]uint64 {
+	limits := make(map[common.Address]uint64, len(minipools))
+	for _, minipoolAddress := range minipools {
+		estimate, ok := estimates[minipoolAddress]
+		if !ok {
+			limits[minipoolAddress] = DISTRIBUTE_CALL_MAX_GAS
+			continue
+		}
+		limits[minipoolAddress] = estimate * uint64(100+dataIn.GasBufferPct) / 100
+	}
+
+	return limits
+}
+
+// totalGas sums the limits of the given minipools
+func totalGas(gasLimits map[common.Address]uint64, minipools []common.Address) uint64 {
+	total := uint64(0)
+	for _, minipoolAddress := range minipools {
+		total += gasLimits[minipoolAddress]
+	}
+
+	return total
+}
+
+func warnGasAnomalies(ctx context.Context, logger *slog.Logger, estimates map[common.Address]uint64) {
+	if !logger.Enabled(ctx, slog.LevelInfo) || len(estimates) == 0 {
+		return
+	}
+
+	values := make([]float64, 0, len(estimates))
+	for _, estimate := range estimates {
+		values = append(values, float64(estimate))
+	}
+	typical := median(values)
+
+	minipools := make([]common.Address, 0, len(estimates))
+	for minipoolAddress := range estimates {
+		minipools = append(minipools, minipoolAddress)
+	}
+	sort.Slice(minipools, func(a, b int) bool {
+		return minipools[a].Cmp(minipools[b]) < 0
+	})
+
+	for _, minipoolAddress := range minipools {
+		estimate := estimates[minipoolAddress]
+		if estimate <= DISTRIBUTE_CALL_MAX_GAS && (len(estimates) < 2 || float64(estimate) <= typical*DISTRIBUTE_GAS_ANOMALY_FACTOR) {
+			continue
+		}
+
+		fmt.Print(colorOrange)
+		fmt.Printf("Note: distributing minipool %s is estimated at %d gas (median %.0f), its state may be unusual.\n", minipoolAddress.Hex(), estimate, typical)
+		fmt.Print(colorReset, "\n")
+	}
+}
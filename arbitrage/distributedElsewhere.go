@@ -0,0 +1,71 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// getMinipoolBalances returns the current balance of each minipool, in the same order
+func getMinipoolBalances(ctx context.Context, dataIn *DataIn, minipools []common.Address) ([]*big.Int, error) {
+	balances := make([]*big.Int, len(minipools))
+	for i, minipool := range minipools {
+		balance, err := dataIn.Client.BalanceAt(ctx, minipool, nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool balance", minipool), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		balances[i] = balance
+	}
+
+	return balances, nil
+}
+
+// getDistributedElsewhere returns the minipools that were distributed while our bundle was pending,
+// e.g. by another party or a separately sent tx. A minipool counts as distributed once it is finalised,
+// or if its balance dropped below half of the balance before the submission.
+func getDistributedElsewhere(ctx context.Context, dataIn *DataIn, minipools []common.Address, balancesBefore []*big.Int) ([]common.Address, error) {
+	var distributed []common.Address
+	for i, minipool := range minipools {
+		instance, err := minipoolDelegate.NewMinipoolDelegate(minipool, dataIn.Client)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to create minipool instance", minipool), err)
+		}
+
+		finalised, err := instance.GetFinalised(nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool finalised state", minipool), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		if finalised {
+			distributed = append(distributed, minipool)
+			continue
+		}
+
+		balance, err := dataIn.Client.BalanceAt(ctx, minipool, nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool balance", minipool), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		threshold := new(big.Int).Div(balancesBefore[i], big.NewInt(2))
+		if balance.Cmp(threshold) < 0 {
+			distributed = append(distributed, minipool)
+		}
+	}
+
+	return distributed, nil
+}
@@ -0,0 +1,93 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// many rpcs limit the block range of eth_getLogs, the scan is split into ranges of this size
+	HISTORY_LOG_CHUNK = 10_000
+	// blocks scanned if no start block is given, about 30 days
+	HISTORY_DEFAULT_BLOCKS = 216_000
+)
+
+// Distribution is one past distributeBalance call of a minipool, taken from its EtherWithdrawalProcessed event
+type Distribution struct {
+	Block        uint64         `json:"block"`
+	Time         uint64         `json:"time"`
+	Minipool     common.Address `json:"minipool"`
+	TxHash       common.Hash    `json:"txHash"`
+	Executed     common.Address `json:"executedBy"`
+	NodeAmount   *big.Int       `json:"nodeAmountWei"`
+	UserAmount   *big.Int       `json:"userAmountWei"`
+	TotalBalance *big.Int       `json:"totalBalanceWei"`
+}
+
+// GetDistributionHistory scans the EtherWithdrawalProcessed events of the minipools between fromBlock and toBlock,
+// both inclusive, ordered by block
+func GetDistributionHistory(ctx context.Context, client *ethclient.Client, minipools []common.Address, fromBlock, toBlock uint64) ([]Distribution, error) {
+	minipoolAbi, err := abi.JSON(strings.NewReader(minipoolDelegate.MinipoolDelegateABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool ABI"), err)
+	}
+	event := minipoolAbi.Events["EtherWithdrawalProcessed"]
+
+	distributions := []Distribution{}
+	if len(minipools) == 0 {
+		return distributions, nil
+	}
+
+	for start := fromBlock; start <= toBlock; start += HISTORY_LOG_CHUNK {
+		end := min(start+HISTORY_LOG_CHUNK-1, toBlock)
+
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: minipools,
+			Topics:    [][]common.Hash{{event.ID}},
+		})
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to get logs of blocks %d to %d", start, end), err)
+		}
+
+		for _, log := range logs {
+			if len(log.Topics) < 2 {
+				continue
+			}
+
+			var processed minipoolDelegate.MinipoolDelegateEtherWithdrawalProcessed
+			err = minipoolAbi.UnpackIntoInterface(&processed, event.Name, log.Data)
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("%s: failed to unpack distribute event", log.TxHash.Hex()), err)
+			}
+
+			distributions = append(distributions, Distribution{
+				Block:        log.BlockNumber,
+				Time:         processed.Time.Uint64(),
+				Minipool:     log.Address,
+				TxHash:       log.TxHash,
+				Executed:     common.BytesToAddress(log.Topics[1].Bytes()),
+				NodeAmount:   processed.NodeAmount,
+				UserAmount:   processed.UserAmount,
+				TotalBalance: processed.TotalBalance,
+			})
+		}
+	}
+
+	sort.SliceStable(distributions, func(a, b int) bool {
+		return distributions[a].Block < distributions[b].Block
+	})
+
+	return distributions, nil
+}
@@ -0,0 +1,89 @@
+package arbitrage
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// a plain ETH transfer to an EOA
+const DONATION_GAS = 21000
+
+// generateDonationTx appends a transfer of DonatePct of the net profit to DonateAddress. The profit arrives at the
+// receiver, so the receiver signs the donation, which only works if it is the node address or the fee payer. The net
+// profit is the expected profit minus the max fees of all txs including the donation itself, so the donation never
+// exceeds the share of the worst case profit. Without a net profit no donation is made and nil is returned.
+func generateDonationTx(logger *slog.Logger, dataIn DataIn, txs []*types.Transaction, expectedProfit, baseGas, tipGas *big.Int) (*types.Transaction, error) {
+	signer := types.LatestSignerForChainID(new(big.Int).SetUint64(dataIn.NetworkId))
+
+	signed := false
+	var nonce uint64
+	for _, tx := range txs {
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to recover sender"), err)
+		}
+		if sender == *dataIn.ReceiverAddress {
+			signed = true
+			nonce = tx.Nonce() + 1
+		}
+	}
+	if !signed {
+		return nil, fmt.Errorf("the donation is sent by the receiver %s, which must be the node address or the fee payer", dataIn.ReceiverAddress.Hex())
+	}
+
+	privateKey := dataIn.NodeAddressPrivateKey
+	if dataIn.FeePayerAddress != nil && *dataIn.FeePayerAddress == *dataIn.ReceiverAddress {
+		privateKey = dataIn.FeePayerPrivateKey
+	}
+
+	netProfit := new(big.Int).Sub(expectedProfit, new(big.Int).Mul(big.NewInt(DONATION_GAS), baseGas))
+	for _, tx := range txs {
+		netProfit.Sub(netProfit, new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), tx.GasFeeCap()))
+	}
+	if netProfit.Sign() <= 0 {
+		logger.Debug("no net profit to donate from", slog.String("netProfit", netProfit.String()))
+		return nil, nil
+	}
+
+	// percent with two decimals
+	amount := new(big.Int).Mul(netProfit, big.NewInt(int64(dataIn.DonatePct*100)))
+	amount.Div(amount, big.NewInt(10000))
+
+	dynTx := &types.DynamicFeeTx{
+		ChainID:   new(big.Int).SetUint64(dataIn.NetworkId),
+		Nonce:     nonce,
+		GasFeeCap: baseGas,
+		GasTipCap: tipGas,
+		To:        dataIn.DonateAddress,
+		Value:     amount,
+		Gas:       DONATION_GAS,
+	}
+
+	donationTx, err := signTransaction(logger, dataIn.Command, privateKey, dataIn.NetworkId, newTx(dataIn.TxType, dynTx))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to sign donation tx"), err)
+	}
+
+	logger.Debug("signed donation tx", slog.String("txHash", donationTx.Hash().Hex()), slog.String("amount", amount.String()))
+	return donationTx, nil
+}
+
+// printDonation discloses the donation in the summary, it is already deducted from the expected profit
+func printDonation(dataIn *DataIn, donationTx *types.Transaction) {
+	if donationTx == nil {
+		if dataIn.DonatePct > 0 {
+			fmt.Println("    Donation: none, there is no net profit to share")
+		}
+		return
+	}
+
+	fmt.Printf("    Donation: %s (%.2f%% of the net profit) to %s, already deducted from the profit above\n",
+		formatEth(weiToEth(donationTx.Value()), dataIn.Precision, dataIn.Scientific),
+		dataIn.DonatePct,
+		donationTx.To().Hex(),
+	)
+}
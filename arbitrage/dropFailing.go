@@ -0,0 +1,41 @@
+package arbitrage
+
+import (
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// failedDistribute is a distribute tx that reverted in the simulation
+type failedDistribute struct {
+	Minipool common.Address
+	Reason   string
+}
+
+// failedDistributes returns the minipools whose distribute tx reverted in the simulation. Other failing txs,
+// e.g. the arbitrage, are not attributed to a minipool and are left out.
+func failedDistributes(bundle *flashbots_client.Bundle, res *flashbots_client.SimulationResultBundle, minipools []common.Address) []failedDistribute {
+	isMinipool := make(map[common.Address]bool, len(minipools))
+	for _, minipoolAddress := range minipools {
+		isMinipool[minipoolAddress] = true
+	}
+
+	var failed []failedDistribute
+	for index, result := range res.Results {
+		if result.Error == "" || index >= len(bundle.Transactions()) {
+			continue
+		}
+
+		to := bundle.Transactions()[index].To()
+		if to == nil || !isMinipool[*to] {
+			continue
+		}
+
+		reason := sanitizeString(result.RevertReason)
+		if reason == "" {
+			reason = result.Error
+		}
+		failed = append(failed, failedDistribute{Minipool: *to, Reason: reason})
+	}
+
+	return failed
+}
@@ -0,0 +1,80 @@
+package arbitrage
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type dumpedBundle struct {
+	NetworkId      uint64           `json:"networkId"`
+	ExpectedProfit string           `json:"expectedProfit,omitempty"`
+	RethShare      string           `json:"rethShare"`
+	RethToBurn     string           `json:"rethToBurn"`
+	Transactions   []dumpedBundleTx `json:"transactions"`
+}
+
+type dumpedBundleTx struct {
+	Index     int    `json:"index"`
+	Type      uint8  `json:"type"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     string `json:"value"`
+	Nonce     uint64 `json:"nonce"`
+	GasLimit  uint64 `json:"gasLimit"`
+	GasFeeCap string `json:"gasFeeCap"`
+	GasTipCap string `json:"gasTipCap"`
+	Data      string `json:"data"`
+	Hash      string `json:"hash"`
+}
+
+// dumpBundle writes a canonical json representation of the built bundle to path.
+// Fields are written in a fixed order and amounts as decimal strings, so two runs can be diffed directly.
+func dumpBundle(path string, dataIn *DataIn, result *BuildResult) error {
+	out := dumpedBundle{
+		NetworkId:    dataIn.NetworkId,
+		RethShare:    result.RethShare.String(),
+		RethToBurn:   result.RethToBurn.String(),
+		Transactions: []dumpedBundleTx{},
+	}
+	if result.ExpectedProfit != nil {
+		out.ExpectedProfit = result.ExpectedProfit.String()
+	}
+
+	for i, tx := range result.Bundle.Transactions() {
+		// the arbitrage tx is signed by the fee payer if one is set
+		from := *dataIn.NodeAddress
+		if sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+			from = sender
+		}
+
+		out.Transactions = append(out.Transactions, dumpedBundleTx{
+			Index:     i,
+			Type:      tx.Type(),
+			From:      from.Hex(),
+			To:        tx.To().Hex(),
+			Value:     tx.Value().String(),
+			Nonce:     tx.Nonce(),
+			GasLimit:  tx.Gas(),
+			GasFeeCap: tx.GasFeeCap().String(),
+			GasTipCap: tx.GasTipCap().String(),
+			Data:      "0x" + hex.EncodeToString(tx.Data()),
+			Hash:      tx.Hash().Hex(),
+		})
+	}
+
+	encoded, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode bundle"), err)
+	}
+
+	err = os.WriteFile(path, append(encoded, '\n'), 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to write bundle file"), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// flags that carry secrets, their values are never written to the emitted script
+var redactedFlags = []string{"node-private-key", "searcher-private-key", "fee-payer", "relay-auth-header"}
+
+// flags replaced by their resolved values in the replay command
+var resolvedFlags = []string{"minipool", "minipools", "node-address", "receiver"}
+
+type emittedRun struct {
+	Command     string            `json:"command"` // replay command with the resolved minipools and addresses
+	Flags       map[string]string `json:"flags"`   // flags as given on the command line
+	NetworkId   uint64            `json:"networkId"`
+	Block       uint64            `json:"block"`
+	NodeAddress string            `json:"nodeAddress"`
+	Receiver    string            `json:"receiver"`
+	Protocol    string            `json:"protocol,omitempty"`
+	TxType      string            `json:"txType"`
+	Minipools   []string          `json:"minipools"`
+	Nonce       uint64            `json:"nonce"`
+	GasFeeCap   string            `json:"gasFeeCap"`
+	GasTipCap   string            `json:"gasTipCap"`
+	TxHashes    []string          `json:"txHashes"`
+}
+
+// emitScript writes all parameters resolved for the built bundle to path, so the run can be reviewed or replayed.
+// The values of private key flags are redacted.
+func emitScript(ctx context.Context, path string, dataIn *DataIn, result *BuildResult) error {
+	block, err := dataIn.Client.BlockNumber(ctx)
+	if err != nil {
+		return errors.Join(errors.New("failed to get block number"), err)
+	}
+
+	txs := result.Bundle.Transactions()
+	if len(txs) == 0 {
+		return errors.New("bundle has no transactions")
+	}
+
+	out := emittedRun{
+		Flags:       redactFlags(dataIn.Flags),
+		NetworkId:   dataIn.NetworkId,
+		Block:       block,
+		NodeAddress: dataIn.NodeAddress.Hex(),
+		Receiver:    dataIn.ReceiverAddress.Hex(),
+		TxType:      string(dataIn.TxType),
+		Minipools:   []string{},
+		Nonce:       txs[0].Nonce(),
+		GasFeeCap:   txs[0].GasFeeCap().String(),
+		GasTipCap:   txs[0].GasTipCap().String(),
+		TxHashes:    []string{},
+	}
+	if !dataIn.LocalReth {
+		out.Protocol = string(dataIn.Protocol)
+	}
+	for _, minipool := range result.IncludedMinipools {
+		out.Minipools = append(out.Minipools, minipool.Hex())
+	}
+	for _, tx := range txs {
+		out.TxHashes = append(out.TxHashes, tx.Hash().Hex())
+	}
+	out.Command = replayCommand(dataIn.Flags, out)
+
+	encoded, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode script"), err)
+	}
+
+	err = os.WriteFile(path, append(encoded, '\n'), 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to write script file"), err)
+	}
+
+	return nil
+}
+
+func redactFlags(flags map[string]string) map[string]string {
+	redacted := make(map[string]string, len(flags))
+	for name, value := range flags {
+		if slices.Contains(redactedFlags, name) {
+			value = "REDACTED"
+		}
+		redacted[name] = value
+	}
+
+	return redacted
+}
+
+// replayCommand rebuilds the command line from the given flags, pinning the resolved values.
+// Private keys are left out, a replay signing with a key needs it added again.
+func replayCommand(flags map[string]string, out emittedRun) string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{"./distribute"}
+	for _, name := range names {
+		if slices.Contains(redactedFlags, name) || slices.Contains(resolvedFlags, name) {
+			continue
+		}
+		args = append(args, shellQuote("--"+name+"="+flags[name]))
+	}
+
+	args = append(args,
+		"--minipools="+strings.Join(out.Minipools, ","),
+		"--node-address="+out.NodeAddress,
+		"--receiver="+out.Receiver,
+	)
+
+	return strings.Join(args, " ")
+}
+
+func shellQuote(arg string) string {
+	if !strings.ContainsAny(arg, " \t\n'\"\\$`;&|<>()*?!#~") {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
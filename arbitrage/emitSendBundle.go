@@ -0,0 +1,82 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+type sendBundleRequest struct {
+	JSONRPC string             `json:"jsonrpc"`
+	ID      int                `json:"id"`
+	Method  string             `json:"method"`
+	Params  []sendBundleParams `json:"params"`
+}
+
+// sendBundleParams mirrors the params the flashbots client sends with eth_sendBundle
+type sendBundleParams struct {
+	Txs               []string `json:"txs"`
+	BlockNumber       string   `json:"blockNumber"`
+	MinTimestamp      int64    `json:"minTimestamp,omitempty"`
+	MaxTimestamp      int64    `json:"maxTimestamp,omitempty"`
+	RevertingTxHashes []string `json:"revertingTxHashes,omitempty"`
+	ReplacementUuid   string   `json:"replacementUuid,omitempty"`
+	Builders          []string `json:"builders,omitempty"`
+}
+
+// emitSendBundle writes the eth_sendBundle requests for the bundle as a JSON-RPC batch, one request per target
+// block starting at the bundle's target, like the bundle is submitted. The txs are signed, nothing is redacted.
+// A path of "-" writes to stdout.
+func emitSendBundle(path string, bundle *flashbots_client.Bundle, validBlocks uint64) error {
+	if bundle.TargetBlockNumber() == 0 {
+		return errors.New("bundle has no target block")
+	}
+
+	txs := []string{}
+	for _, tx := range bundle.Transactions() {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return errors.Join(errors.New("failed to encode tx"), err)
+		}
+		txs = append(txs, hexutil.Encode(raw))
+	}
+
+	requests := make([]sendBundleRequest, 0, validBlocks)
+	for i := uint64(0); i < validBlocks; i++ {
+		requests = append(requests, sendBundleRequest{
+			JSONRPC: "2.0",
+			ID:      int(i) + 1,
+			Method:  "eth_sendBundle",
+			Params: []sendBundleParams{{
+				Txs:               txs,
+				BlockNumber:       fmt.Sprintf("0x%x", bundle.TargetBlockNumber()+i),
+				MinTimestamp:      bundle.MinTimestamp(),
+				MaxTimestamp:      bundle.MaxTimestamp(),
+				RevertingTxHashes: bundle.RevertingTxHashes(),
+				ReplacementUuid:   bundle.ReplacementUuid(),
+				Builders:          bundle.Builders(),
+			}},
+		})
+	}
+
+	encoded, err := json.MarshalIndent(requests, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode eth_sendBundle requests"), err)
+	}
+	encoded = append(encoded, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(encoded)
+	} else {
+		err = os.WriteFile(path, encoded, 0644)
+	}
+	if err != nil {
+		return errors.Join(errors.New("failed to write eth_sendBundle requests"), err)
+	}
+
+	return nil
+}
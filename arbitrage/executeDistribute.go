@@ -7,9 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/big"
 	"os"
 	"rocketpoolArbitrage/rocketpoolContracts/storage"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -19,13 +21,170 @@ import (
 	"github.com/0xtrooper/flashbots_client"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn) error {
+	if dataIn.OtelEndpoint == "" {
+		return executeDistributeAll(ctx, logger, dataIn)
+	}
+
+	tracer := newTracer(dataIn.OtelEndpoint)
+	ctx, root := tracer.startRoot(ctx, "ExecuteDistribute")
+	root.setAttr("minipools", len(dataIn.MinipoolAddresses))
+	if dataIn.Label != "" {
+		root.setAttr("label", dataIn.Label)
+	}
+
+	err := executeDistributeAll(ctx, logger, dataIn)
+	root.finish(err)
+
+	// the trace is informational, the run itself is already done
+	exportErr := tracer.export(context.Background())
+	if exportErr != nil {
+		logger.Warn("failed to export trace", slog.String("endpoint", dataIn.OtelEndpoint), slog.String("error", exportErr.Error()))
+	}
+
+	return err
+}
+
+func executeDistributeAll(ctx context.Context, logger *slog.Logger, dataIn *DataIn) error {
+	if dataIn.session == nil {
+		dataIn.session = &session{valueAtRisk: big.NewInt(0), ethSpent: big.NewInt(0)}
+	}
+
+	if dataIn.Resume {
+		state, err := loadRunState(dataIn.StateFile)
+		if err != nil {
+			return errors.Join(errors.New("failed to load state for resume"), err)
+		}
+
+		remaining := withoutAddresses(dataIn.MinipoolAddresses, state.Distributed)
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Printf("Resuming from %s: skipping %d already distributed minipools, %d remaining.\n\n",
+				dataIn.StateFile,
+				len(dataIn.MinipoolAddresses)-len(remaining),
+				len(remaining),
+			)
+		}
+
+		if len(remaining) == 0 {
+			fmt.Println("All minipools were already distributed, nothing to do.")
+			return nil
+		}
+		dataIn.MinipoolAddresses = remaining
+	}
+
+	if dataIn.MinMinipoolAge > 0 || dataIn.MaxMinipoolAge > 0 {
+		kept, err := filterMinipoolsByAge(ctx, logger, dataIn, dataIn.MinipoolAddresses)
+		if err != nil {
+			return errors.Join(errors.New("failed to filter minipools by age"), err)
+		}
+
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Printf("Age filter: skipping %d minipools, %d remaining.\n\n", len(dataIn.MinipoolAddresses)-len(kept), len(kept))
+		}
+
+		if len(kept) == 0 {
+			fmt.Println("No minipool matches the age filter, nothing to do.")
+			return nil
+		}
+		dataIn.MinipoolAddresses = kept
+	}
+
+	// a single distribute does not amortize the arbitrage gas, waiting for more exits is usually cheaper
+	if dataIn.MinMinipools > 0 && len(dataIn.MinipoolAddresses) < dataIn.MinMinipools {
+		if !dataIn.Force {
+			return fmt.Errorf("%w: %d minipools available, at least %d required. Wait for more exits or use --force", ErrTooFewMinipools, len(dataIn.MinipoolAddresses), dataIn.MinMinipools)
+		}
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Printf("Only %d of the required %d minipools available, continuing because of --force.\n\n", len(dataIn.MinipoolAddresses), dataIn.MinMinipools)
+		}
+	}
+
+	var reports []*RunReport
+	var err error
+	if dataIn.BundleSize > 0 && len(dataIn.MinipoolAddresses) > dataIn.BundleSize {
+		reports, err = executeSplitDistribute(ctx, logger, dataIn)
+	} else {
+		var report *RunReport
+		report, err = executeDistributeRun(ctx, logger, dataIn)
+		reports = []*RunReport{report}
+	}
+
+	if dataIn.JSONOutput != "" {
+		jsonErr := writeJSONReport(dataIn.JSONOutput, dataIn, reports)
+		if jsonErr != nil {
+			logger.Warn("failed to write json output", slog.String("file", dataIn.JSONOutput), slog.String("error", jsonErr.Error()))
+		}
+	}
+
+	// e.g. not included or aborted after a known simulation failure, reported as failure in the exit code
+	if err == nil {
+		for _, report := range reports {
+			// a bundle deferred by the max total value is not a failure
+			if report != nil && !report.Succeeded() && !errors.Is(report.Err, ErrMaxTotalValueReached) {
+				return ErrRunNotSucceeded
+			}
+		}
+	}
+
+	return err
+}
+
+// executeDistributeRun builds, simulates and sends a single bundle and runs the hooks afterwards
+func executeDistributeRun(ctx context.Context, logger *slog.Logger, dataIn *DataIn) (*RunReport, error) {
+	report := &RunReport{
+		MinipoolCount: len(dataIn.MinipoolAddresses),
+		Minipools:     dataIn.MinipoolAddresses,
+	}
+
+	ctx, span := startSpan(ctx, "distribute")
+
+	var err error
+	if len(dataIn.FeeLadder) > 0 {
+		err = executeFeeLadder(ctx, logger, dataIn, report)
+	} else {
+		err = executeDistribute(ctx, logger, dataIn, report)
+	}
+	report.Err = err
+
+	span.setAttr("minipools", report.MinipoolCount)
+	span.setEthAttr("expectedProfit", report.ExpectedProfit)
+	span.setEthAttr("rethShare", report.RethShare)
+	span.setAttr("included", report.Included)
+	span.setAttr("dryRun", report.DryRun)
+	span.finish(err)
+
+	if dataIn.Timings && logger.Enabled(ctx, slog.LevelInfo) {
+		printTimings(report.Timings)
+	}
+
+	runHooks(ctx, logger, dataIn, report)
+
+	if dataIn.StateFile != "" {
+		stateErr := updateRunState(dataIn.StateFile, report)
+		if stateErr != nil {
+			logger.Warn("failed to update state file", slog.String("file", dataIn.StateFile), slog.String("error", stateErr.Error()))
+		}
+	}
+
+	return report, err
+}
+
+func executeDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn, report *RunReport) error {
 	logger.With(slog.String("function", "Simulate"))
 
+	if len(dataIn.MinipoolAddresses) == 0 {
+		return reportNothingToDistribute(dataIn, report)
+	}
+
+	_, verifySpan := startSpan(ctx, "VerifyInputData")
+	verifyTimer := startPhase(dataIn, report, "VerifyInputData")
 	err := VerifyInputData(ctx, logger, dataIn)
+	verifySpan.finish(err)
+	verifyTimer.stop()
 	if err != nil {
 		return errors.Join(errors.New("failed to verify input data"), err)
 	}
@@ -77,22 +236,93 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 	}
 
 	// build bundle
-	var bundle *flashbots_client.Bundle
-	var rethToBurn, rETHShare, expectedProfit *big.Int
+	_, buildSpan := startSpan(ctx, "BuildCall")
+	buildTimer := startPhase(dataIn, report, "BuildCall")
+	var result *BuildResult
 	if dataIn.LocalReth {
-		bundle, rethToBurn, rETHShare, err = BuildCallLocalReth(ctx, logger, *dataIn)
+		result, err = BuildCallLocalReth(ctx, logger, *dataIn)
+		buildSpan.finish(err)
+		buildTimer.stop()
+		if errors.Is(err, ErrNothingToDistribute) {
+			return reportNothingToDistribute(dataIn, report)
+		}
 		if err != nil {
 			return errors.Join(errors.New("failed to build call"), err)
 		}
 	} else {
-		bundle, expectedProfit, err = BuildCall(ctx, logger, *dataIn)
+		result, err = BuildCall(ctx, logger, *dataIn)
+		buildSpan.finish(err)
+		buildTimer.stop()
+		if errors.Is(err, ErrNothingToDistribute) {
+			return reportNothingToDistribute(dataIn, report)
+		}
 		if err != nil {
 			return errors.Join(errors.New("failed to build call"), err)
 		}
 	}
+	bundle := result.Bundle
+	expectedProfit := result.ExpectedProfit
+	buildSpan.setAttr("minipools", len(result.IncludedMinipools))
+	buildSpan.setEthAttr("expectedProfit", expectedProfit)
+	report.ExpectedProfit = expectedProfit
+	report.RethShare = result.RethShare
+	report.MinipoolCount = len(result.IncludedMinipools)
+	report.Minipools = result.IncludedMinipools
+	report.RethToBurn = result.RethToBurn
+	report.ExchangeRate = result.ExchangeRate
+	if result.ExpectedProfit != nil && result.PoolPriceBefore > 0 {
+		report.PoolPrice = &PoolPriceChange{
+			Before:         result.PoolPriceBefore,
+			After:          result.PoolPriceAfter,
+			ImpactPct:      result.PriceImpactPct,
+			DiscountBefore: result.PoolDiscountBeforePct,
+			DiscountAfter:  result.PoolDiscountAfterPct,
+		}
+	}
+
+	if dataIn.DumpBundle != "" {
+		err = dumpBundle(dataIn.DumpBundleFile, dataIn, result)
+		if err != nil {
+			return errors.Join(errors.New("failed to dump bundle"), err)
+		}
+		logger.Debug("dumped bundle", slog.String("file", dataIn.DumpBundleFile))
+	}
+
+	if dataIn.EmitScript != "" {
+		err = emitScript(ctx, dataIn.EmitScript, dataIn, result)
+		if err != nil {
+			return errors.Join(errors.New("failed to emit script"), err)
+		}
+		logger.Debug("emitted script", slog.String("file", dataIn.EmitScript))
+	}
+
+	// the distribute txs do not depend on the arbitrage, allowing it to revert lets them land on their own
+	if dataIn.DistributePriority && !dataIn.LocalReth {
+		bundle.SetRevertingTxHash(result.ArbitrageTx.Hash().Hex())
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Print(colorOrange, "Distribute priority: the arbitrage tx may revert without blocking the distribution. In that case the arbitrage profit is forgone and its gas is still paid.", colorReset, "\n\n")
+		}
+	}
+
+	// a bundle above the block gas limit would simply never be included
+	err = checkBlockGasLimit(ctx, dataIn, bundle)
+	if err != nil {
+		return err
+	}
+
+	// the submitted txs are compared against these right before sending
+	simulated, err := snapshotBundle(bundle)
+	if err != nil {
+		return err
+	}
 
 	logger.Debug("created flashbots client")
-	success, bundleHash, arbTxHash, err := simulateBundle(logger, dataIn, bundle)
+	_, simulateSpan := startSpan(ctx, "SimulateBundle")
+	simulateTimer := startPhase(dataIn, report, "SimulateBundle")
+	success, bundleHash, arbTxHash, simulatedGas, relaySimulation, err := simulateBundle(logger, dataIn, bundle, result.ArbitrageTx, result.RethShare)
+	simulateSpan.setAttr("success", success)
+	simulateSpan.finish(err)
+	simulateTimer.stop()
 	if err != nil {
 		// handle known revert reasons, user was updated in the simulateBundle function
 		if strings.EqualFold(err.Error(), "Paraswap failed") || strings.EqualFold(err.Error(), "Insufficient ETH balance for exchange") {
@@ -100,14 +330,91 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 		}
 		return errors.Join(errors.New("failed to simulate bundle"), err)
 	}
+	report.BundleHash = bundleHash
+	report.TxHash = arbTxHash
+	report.Simulation = simulatedTxs(relaySimulation)
+
+	// drop the minipools whose distribute reverts, e.g. distributed elsewhere, and rebuild with the rest
+	if !success && dataIn.DropFailing {
+		failed := failedDistributes(bundle, relaySimulation, result.IncludedMinipools)
+		if len(failed) > 0 && len(failed) < len(result.IncludedMinipools) {
+			dropped := make([]common.Address, len(failed))
+			for i, distribute := range failed {
+				dropped[i] = distribute.Minipool
+				logger.Warn("dropping minipool, its distribute tx reverts in the simulation",
+					slog.String("minipool", distribute.Minipool.Hex()),
+					slog.String("reason", distribute.Reason),
+				)
+			}
+			if logger.Enabled(ctx, slog.LevelInfo) {
+				fmt.Print(colorOrange)
+				fmt.Printf("Dropping %d of %d minipools whose distribute tx reverts, rebuilding the bundle with the remaining %d:\n", len(failed), len(result.IncludedMinipools), len(result.IncludedMinipools)-len(failed))
+				for _, distribute := range failed {
+					fmt.Printf("    %s: %s\n", distribute.Minipool.Hex(), distribute.Reason)
+				}
+				fmt.Print(colorReset, "\n")
+			}
+
+			report.Dropped = append(report.Dropped, dropped...)
+			dataIn.MinipoolAddresses = withoutAddresses(result.IncludedMinipools, dropped)
+			return executeDistribute(ctx, logger, dataIn, report)
+		}
+	}
+
+	// without a profit to protect, e.g. with distribute priority, a mismatch is only reported
+	if success && !dataIn.LocalReth && expectedProfit != nil && expectedProfit.Sign() > 0 {
+		err = verifyProfitRecipient(ctx, logger, dataIn, bundle, result.ArbitrageTx, expectedProfit)
+		switch {
+		case errors.Is(err, ErrProfitRecipientMismatch) && dataIn.CheckProfit && !dataIn.DistributePriority:
+			return err
+		case errors.Is(err, ErrProfitRecipientMismatch):
+			fmt.Print(colorOrange, "Warning: ", err.Error(), colorReset, "\n\n")
+		case err != nil:
+			logger.Warn("failed to verify that the profit reaches the receiver", slog.String("error", err.Error()))
+		}
+	}
 
-	maxBundleFees, maxArbitrageFees := evalGasPrices(bundle)
+	maxBundleFees, maxArbitrageFees := evalGasPrices(bundle, result.ArbitrageTx)
+	spanFromContext(ctx).setEthAttr("maxBundleFees", maxBundleFees)
+	spanFromContext(ctx).setEthAttr("maxArbitrageFees", maxArbitrageFees)
+
+	// advisory only, a failure does not stop the run
+	inclusionBlocks, inclusionErr := estimateInclusionBlocks(ctx, dataIn, result.ArbitrageTx.GasFeeCap(), result.ArbitrageTx.GasTipCap())
+	if inclusionErr != nil {
+		logger.Warn("failed to estimate time to inclusion", slog.String("error", inclusionErr.Error()))
+	}
+
+	var feeTrend *FeeTrend
+	if !dataIn.LocalReth {
+		var trendErr error
+		feeTrend, trendErr = estimateFeeTrend(ctx, dataIn, bundle, simulatedGas, expectedProfit)
+		if trendErr != nil {
+			logger.Warn("failed to estimate the fee trend", slog.String("error", trendErr.Error()))
+		}
+	}
+
+	// the builder payment is based on the next base fee, taken from the fee trend if there is one
+	var nextBaseFee *big.Int
+	if feeTrend != nil {
+		nextBaseFee = feeTrend.BaseFee
+	} else if logger.Enabled(ctx, slog.LevelInfo) {
+		var baseFeeErr error
+		nextBaseFee, baseFeeErr = getNextBaseFee(ctx, dataIn)
+		if baseFeeErr != nil {
+			logger.Warn("failed to get the next base fee", slog.String("error", baseFeeErr.Error()))
+		}
+	}
+
+	totalDistributed, err := getTotalBalance(ctx, dataIn, result.IncludedMinipools)
+	if err != nil {
+		return errors.Join(errors.New("failed to get distributed balance"), err)
+	}
 
 	// print update based on user selection
 	if logger.Enabled(ctx, slog.LevelInfo) {
 		if dataIn.LocalReth {
-			rEthBurnedFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(rethToBurn), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
-			ethReceivedFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(rETHShare), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+			rEthBurnedFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(result.RethToBurn), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+			ethReceivedFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(result.RethShare), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
 			expectedFeeFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(maxBundleFees), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
 			fmt.Print("Simulated bundle (")
 			if success {
@@ -116,7 +423,15 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 				fmt.Print(string(colorRed), "failed", string(colorReset))
 			}
 			fmt.Println("):")
-			fmt.Printf("    Expected to burn %.6f rETH for %.6f ETH, with a tx fee of %.6f\n", rEthBurnedFloat, ethReceivedFloat, expectedFeeFloat)
+			fmt.Printf("    Expected to burn %s rETH for %s ETH, with a tx fee of %s\n",
+				formatEth(rEthBurnedFloat, dataIn.Precision, dataIn.Scientific),
+				formatEth(ethReceivedFloat, dataIn.Precision, dataIn.Scientific),
+				formatEth(expectedFeeFloat, dataIn.Precision, dataIn.Scientific),
+			)
+			printExchangeRateSource(result.ExchangeRate)
+			printBuilderPayment(dataIn, bundle, simulatedGas, nextBaseFee, relaySimulation)
+			printGasUtilization(bundle, simulatedGas)
+			printInclusionEstimate(inclusionBlocks, inclusionErr)
 		} else {
 			maxBundleFeesFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(maxBundleFees), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
 			maxArbitrageFeesFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(maxArbitrageFees), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
@@ -128,16 +443,94 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 				fmt.Print(string(colorRed), "failed", string(colorReset))
 			}
 			fmt.Println("):")
-			fmt.Printf("    Expected profit after fees: %.6f, with a tx fee of %.6f\n", expectedProfitFloat-maxBundleFeesFloat, maxBundleFeesFloat)
-			fmt.Printf("    Expected profit after arbitrage fees: %.6f, with a tx fee of %.6f (interesting if you want to distribute regardless)\n\n", expectedProfitFloat-maxArbitrageFeesFloat, maxArbitrageFeesFloat)
+			fmt.Printf("    Expected profit after fees: %s, with a tx fee of %s\n",
+				formatEth(expectedProfitFloat-maxBundleFeesFloat, dataIn.Precision, dataIn.Scientific),
+				formatEth(maxBundleFeesFloat, dataIn.Precision, dataIn.Scientific),
+			)
+			fmt.Printf("    Expected profit after arbitrage fees: %s, with a tx fee of %s (interesting if you want to distribute regardless)\n",
+				formatEth(expectedProfitFloat-maxArbitrageFeesFloat, dataIn.Precision, dataIn.Scientific),
+				formatEth(maxArbitrageFeesFloat, dataIn.Precision, dataIn.Scientific),
+			)
+			fmt.Printf("    Break-even discount: %.4f%%, current discount: %.4f%%\n",
+				discountPercent(maxBundleFees, result.RethShare),
+				discountPercent(expectedProfit, result.RethShare),
+			)
+			fmt.Printf("    Expected profit after fees: %.2f bps of the %s ETH distributed\n",
+				basisPoints(new(big.Int).Sub(expectedProfit, maxBundleFees), totalDistributed),
+				formatEth(weiToEth(totalDistributed), dataIn.Precision, dataIn.Scientific),
+			)
+			if dataIn.FeeRefundPct > 0 {
+				refundFloat := weiToEth(expectedFeeRefund(bundle, dataIn.FeeRefundPct))
+				fmt.Printf("    Expected fee refund: %s (%.0f%% of the priority fees), profit after fees and refund: %s\n",
+					formatEth(refundFloat, dataIn.Precision, dataIn.Scientific),
+					dataIn.FeeRefundPct,
+					formatEth(expectedProfitFloat-maxBundleFeesFloat+refundFloat, dataIn.Precision, dataIn.Scientific),
+				)
+			}
+			printDonation(dataIn, result.DonationTx)
+			printUsdValues(result.UsdPrice, expectedProfitFloat-maxBundleFeesFloat, maxBundleFeesFloat)
+			printExchangeRateSource(result.ExchangeRate)
+			printPoolPriceChange(report.PoolPrice)
+			printRelaySimulation(dataIn, relaySimulation, expectedProfit, maxBundleFees)
+			printFeeTrend(feeTrend)
+			printBuilderPayment(dataIn, bundle, simulatedGas, nextBaseFee, relaySimulation)
+			printGasUtilization(bundle, simulatedGas)
+			printInclusionEstimate(inclusionBlocks, inclusionErr)
 		}
 	}
 
+	if !dataIn.LocalReth && logger.Enabled(ctx, slog.LevelInfo) {
+		warnProfitConcentration(ctx, logger, dataIn, result)
+	}
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		warnRplImpact(ctx, logger, dataIn, result.IncludedMinipools)
+	}
+
+	if dataIn.ShowApr && !dataIn.LocalReth && logger.Enabled(ctx, slog.LevelInfo) {
+		printAprImpact(ctx, logger, dataIn, result.IncludedMinipools, new(big.Int).Sub(expectedProfit, maxBundleFees))
+	}
+
+	// a single number for shell scripts, the profit check decides the exit code
+	if dataIn.ProfitOutput != nil {
+		report.DryRun = true
+		fmt.Fprintln(dataIn.ProfitOutput, formatEth(weiToEth(new(big.Int).Sub(expectedProfit, maxBundleFees)), dataIn.Precision, dataIn.Scientific))
+		if !success {
+			return errors.New("bundle simulation failed")
+		}
+		err = checkProfit(dataIn, expectedProfit, maxBundleFees, maxArbitrageFees)
+		if err != nil || dataIn.AutoThreshold == 0 {
+			return err
+		}
+		return checkAutoThreshold(ctx, logger, dataIn, bundle, simulatedGas, expectedProfit)
+	}
+
+	// monitoring only, the decision is reported and nothing is prompted or sent
+	if dataIn.Advisory {
+		report.DryRun = true
+		return reportAdvisory(ctx, logger, dataIn, bundle, success, simulatedGas, expectedProfit, maxBundleFees, maxArbitrageFees, len(result.IncludedMinipools))
+	}
+
 	// print txs:
 	// - this will always be printed if the user is using local rETH to allow confirming the burn
 	// - if dry-run is set, this will be printed regardless of the user's choice and the txs will not be sent
 	if dataIn.DryRun || dataIn.LocalReth {
+		var validationErr error
 		if dataIn.DryRun {
+			validationErr = validateBundle(ctx, logger, dataIn, bundle)
+			if validationErr != nil {
+				fmt.Print(colorRed, "Bundle validation failed: ", validationErr.Error(), colorReset, "\n\n")
+			} else {
+				fmt.Print(colorGreen, "Bundle validation passed: ", colorReset, "signatures, nonces and fees are valid and the relay accepted the bundle for the next block.\n\n")
+			}
+
+			if dataIn.EmitSendBundle != "" {
+				err = emitDryRunSendBundle(ctx, dataIn, bundle)
+				if err != nil {
+					return err
+				}
+			}
+
 			fmt.Println("Dry run. Would have sent the following bundle:")
 		}
 
@@ -147,7 +540,11 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 			tipGwei, _ := new(big.Float).Quo(new(big.Float).SetInt(tx.GasTipCap()), new(big.Float).SetInt(big.NewInt(1e9))).Float64()
 
 			fmt.Printf("Transaction %d:\n", i+1)
-			fmt.Printf("    From: %s\n", dataIn.NodeAddress.Hex())
+			from := *dataIn.NodeAddress
+			if sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+				from = sender
+			}
+			fmt.Printf("    From: %s\n", from.Hex())
 			fmt.Printf("    To: %s\n", tx.To().Hex())
 			fmt.Printf("    Value: %s\n", tx.Value().String())
 			fmt.Printf("    Gas Limit: %d\n", tx.Gas())
@@ -159,6 +556,10 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 
 		// only return if dry-run is set
 		if dataIn.DryRun {
+			report.DryRun = true
+			if validationErr != nil {
+				return errors.Join(errors.New("bundle validation failed"), validationErr)
+			}
 			return nil
 		}
 	}
@@ -168,24 +569,106 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 		return errors.New("bundle simulation failed")
 	}
 
-	// this checks if a bundle makes sense to make arbitrage profits
-	if dataIn.CheckProfit && !dataIn.CheckProfitIgnoreDistributeCost && expectedProfit.Cmp(maxBundleFees) < 0 {
-		return errors.New("expected profit is less than max bundle fees")
+	// with distribute priority the distribution goes ahead regardless of the profit
+	if !dataIn.DistributePriority {
+		err = checkProfit(dataIn, expectedProfit, maxBundleFees, maxArbitrageFees)
+		if err != nil {
+			return err
+		}
+
+		if dataIn.AutoThreshold > 0 {
+			err = checkAutoThreshold(ctx, logger, dataIn, bundle, simulatedGas, expectedProfit)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	// this checks if a bundle makes sense if the user wants to distribute
-	// aka. distribute gas needs to be paid one way or another
-	if dataIn.CheckProfit && dataIn.CheckProfitIgnoreDistributeCost && expectedProfit.Cmp(maxArbitrageFees) < 0 {
-		return errors.New("expected profit is less than max arbitrage fees")
+	// a profit this large is more likely a wrong decimal, bad route or stale reserves than a real opportunity
+	if dataIn.MaxPlausibleProfit != nil && expectedProfit != nil && expectedProfit.Cmp(dataIn.MaxPlausibleProfit) > 0 {
+		fmt.Print(colorRed)
+		fmt.Printf("Warning: the expected profit of %s ETH is above the plausible maximum of %s ETH, this likely indicates a modeling error.\n",
+			formatEth(weiToEth(expectedProfit), dataIn.Precision, dataIn.Scientific),
+			formatEth(weiToEth(dataIn.MaxPlausibleProfit), dataIn.Precision, dataIn.Scientific),
+		)
+		fmt.Print(colorReset, "\n")
+
+		if !dataIn.Force {
+			return fmt.Errorf("%w: %.6f ETH expected, at most %.6f ETH plausible. Check the route and prices or use --force", ErrImplausibleProfit,
+				weiToEth(expectedProfit),
+				weiToEth(dataIn.MaxPlausibleProfit),
+			)
+		}
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Println("Continuing because of --force.")
+			fmt.Println()
+		}
+	}
+
+	// portfolio level limit across all bundles of this invocation
+	valueAtRisk := new(big.Int).Add(totalDistributed, maxBundleFees)
+	if dataIn.MaxTotalValue != nil && new(big.Int).Add(dataIn.session.valueAtRisk, valueAtRisk).Cmp(dataIn.MaxTotalValue) > 0 {
+		return fmt.Errorf("%w: %.6f ETH already at risk, this bundle adds %.6f ETH, limit is %.6f ETH", ErrMaxTotalValueReached,
+			weiToEth(dataIn.session.valueAtRisk),
+			weiToEth(valueAtRisk),
+			weiToEth(dataIn.MaxTotalValue),
+		)
+	}
+
+	// a blunt cap on what leaves the node wallet, against a misconfigured tx sending more than intended
+	ethSpend, err := nodeEthSpend(dataIn, bundle)
+	if err != nil {
+		return err
+	}
+	if dataIn.MaxEthSpend != nil && new(big.Int).Add(dataIn.session.ethSpent, ethSpend).Cmp(dataIn.MaxEthSpend) > 0 {
+		return fmt.Errorf("%w: %.6f ETH already spent, this bundle spends up to %.6f ETH from the node wallet, limit is %.6f ETH", ErrMaxEthSpendReached,
+			weiToEth(dataIn.session.ethSpent),
+			weiToEth(ethSpend),
+			weiToEth(dataIn.MaxEthSpend),
+		)
 	}
 
 	// ask for user confirmation
-	if !dataIn.SkipConfirmation && !waitForUserConfirmation(dataIn.LocalReth) {
-		return errors.New("user did not confirm to proceed")
+	// for large amounts the user has to type the amount instead of y/n
+	requiredAmount := ""
+	if dataIn.ConfirmAmountAbove > 0 {
+		rethShareFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(result.RethShare), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+		if rethShareFloat > dataIn.ConfirmAmountAbove {
+			requiredAmount = fmt.Sprintf("%.4f", rethShareFloat)
+		}
+	}
+
+	if !dataIn.SkipConfirmation {
+		printConfirmationSummary(ctx, logger, dataIn, result, expectedProfit, maxBundleFees)
+		if !waitForUserConfirmation(dataIn.LocalReth, requiredAmount) {
+			return errors.New("user did not confirm to proceed")
+		}
+	}
+
+	// another tx of a signer may have landed while building or waiting for the confirmation, the bundle is then
+	// rebuilt, signed with the new nonces and simulated again
+	signer, err := staleNonce(ctx, dataIn, bundle)
+	if err != nil {
+		return err
+	}
+	if signer != nil {
+		if dataIn.nonceRefreshes >= MAX_NONCE_REFRESHES {
+			return fmt.Errorf("nonce of %s changed again after %d rebuilds, check for other txs sent from it", signer.Hex(), dataIn.nonceRefreshes)
+		}
+		dataIn.nonceRefreshes++
+
+		logger.Warn("nonce changed since the bundle was built, rebuilding", slog.String("signer", signer.Hex()), slog.Int("attempt", dataIn.nonceRefreshes))
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Print(colorOrange, "The nonce of ", signer.Hex(), " changed since the bundle was built, rebuilding and simulating it again.", colorReset, "\n\n")
+		}
+		return executeDistribute(ctx, logger, dataIn, report)
 	}
+	dataIn.nonceRefreshes = 0
 
-	// add more builders to improve chance to be included
-	bundle.UseAllBuilders(dataIn.NetworkId)
+	// add more builders to improve chance to be included, unless only the default relay should see the bundle
+	if !dataIn.NoBroadcast {
+		bundle.UseAllBuilders(dataIn.NetworkId)
+	}
 
 	// set target block number
 	blockNumber, err := dataIn.Client.BlockNumber(ctx)
@@ -194,15 +677,132 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 	}
 	bundle.SetTargetBlockNumber(blockNumber + 1)
 
-	fmt.Printf("\nSent bundle with hash: %s. Waiting for up to one minute to see if the transaction is included...\n\n", bundleHash)
+	// the relay api has no block range, a copy of the bundle is sent for each of the next blocks at once
+	validBlocks := dataIn.ValidBlocks
+	if validBlocks == 0 {
+		validBlocks = DEFAULT_VALID_BLOCKS
+	}
+
+	// only submit exactly what was simulated
+	err = verifySubmission(simulated, bundle, validBlocks)
+	if err != nil {
+		return err
+	}
+
+	if dataIn.EmitSendBundle != "" {
+		err = emitSendBundle(dataIn.EmitSendBundle, bundle, validBlocks)
+		if err != nil {
+			return err
+		}
+		logger.Debug("emitted eth_sendBundle requests", slog.String("file", dataIn.EmitSendBundle))
+	}
+
+	var pendingSubmission *submission
+	if dataIn.DedupeWindow > 0 {
+		pendingSubmission, err = guardDuplicateSubmission(logger, dataIn, result.IncludedMinipools, blockNumber+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	// used to tell if the minipools were distributed by someone else in case the bundle is not included
+	balancesBefore, err := getMinipoolBalances(ctx, dataIn, result.IncludedMinipools)
+	if err != nil {
+		logger.Warn("failed to get minipool balances before submission", slog.String("error", err.Error()))
+	}
+
+	timeout := time.Duration(validBlocks)*SLOT_DURATION + INCLUSION_TIMEOUT_MARGIN
+
+	fmt.Printf("\nSent bundle with hash: %s for the next %d blocks. Waiting for up to %s to see if the transaction is included...\n\n", bundleHash, validBlocks, timeout)
 
-	timeoutContext, cancel := context.WithTimeout(ctx, time.Second*70)
-	successfullyIncluded, err := dataIn.FbClient.SendNBundleAndWait(timeoutContext, bundle, 4)
+	timeoutContext, cancel := context.WithTimeout(ctx, timeout)
+	_, inclusionSpan := startSpan(ctx, "WaitForInclusion")
+	inclusionTimer := startPhase(dataIn, report, "WaitForInclusion")
+	reorgWatcher := watchReorgs(timeoutContext, logger, dataIn, bundle)
+	successfullyIncluded, err := dataIn.FbClient.SendNBundleAndWait(timeoutContext, bundle, validBlocks)
 	cancel()
+	<-reorgWatcher
+	inclusionSpan.setAttr("validBlocks", validBlocks)
+	inclusionSpan.setAttr("included", successfullyIncluded)
+	inclusionSpan.finish(err)
+	inclusionTimer.stop()
+
+	// the relay wait only tells if it saw the txs, with --inclusion-confirmations the chain decides
+	var inclusion string
+	var sealedBy int
+	if err == nil && dataIn.InclusionConfirmations > 0 {
+		fmt.Printf("Checking the inclusion on chain, requiring %d confirmations...\n", dataIn.InclusionConfirmations)
+		inclusion, report.InclusionBlock, sealedBy = confirmInclusion(ctx, logger, dataIn, bundle, validBlocks, arbTxHash)
+		report.Inclusion = inclusion
+		successfullyIncluded = inclusion == inclusionLanded
+		logger.Debug("inclusion check", slog.String("inclusion", inclusion), slog.Uint64("block", report.InclusionBlock), slog.Int("sealedByBuilders", sealedBy))
+	}
+
+	if pendingSubmission != nil {
+		switch {
+		case err != nil:
+			pendingSubmission.Status = submissionFailed
+		case inclusion == inclusionUnknown:
+			// it may still land, keep blocking resubmissions within the dedupe window
+			pendingSubmission.Status = submissionPending
+		case successfullyIncluded:
+			pendingSubmission.Status = submissionIncluded
+		default:
+			pendingSubmission.Status = submissionNotIncluded
+		}
+
+		recordErr := recordSubmission(dataIn.SubmissionLog, *pendingSubmission, dataIn.DedupeWindow)
+		if recordErr != nil {
+			logger.Warn("failed to update submission log", slog.String("error", recordErr.Error()))
+		}
+	}
+
 	if err != nil {
 		return errors.Join(errors.New("failed to wait for bundle inclusion"), err)
 	}
 
+	if dataIn.LogFile != "" {
+		record := newGasLogRecord(ctx, dataIn, bundle, result.ArbitrageTx, result.DonationTx, simulatedGas, bundleHash, successfullyIncluded)
+		logErr := appendGasLog(dataIn.LogFile, record)
+		if logErr != nil {
+			logger.Warn("failed to write log file", slog.String("file", dataIn.LogFile), slog.String("error", logErr.Error()))
+		}
+	}
+
+	// the minipools may have been distributed by this very bundle, the check for other parties would be misleading
+	if inclusion == inclusionUnknown {
+		fmt.Print(colorOrange)
+		fmt.Printf("The bundle was submitted, but it could not be confirmed within %s whether it landed.\n", dataIn.ConfirmationTimeout)
+		if sealedBy > 0 {
+			fmt.Printf("The relay reports %d builder(s) sealed a block with the bundle.\n", sealedBy)
+		}
+		fmt.Printf("Check the tx before running again: %s\n", explorerTxUrl(dataIn, arbTxHash))
+		fmt.Print(colorReset, "\n")
+		return ErrInclusionUnknown
+	}
+
+	if !successfullyIncluded && balancesBefore != nil {
+		distributed, err := getDistributedElsewhere(ctx, dataIn, result.IncludedMinipools, balancesBefore)
+		if err != nil {
+			logger.Warn("failed to check if the minipools were distributed elsewhere", slog.String("error", err.Error()))
+		} else if len(distributed) == len(result.IncludedMinipools) {
+			report.DistributedElsewhere = true
+			fmt.Print(colorOrange)
+			fmt.Println("The bundle was not included, but all minipools have been distributed in the meantime (e.g. by another party).")
+			fmt.Println("No need to run again.")
+			fmt.Print(colorReset, "\n")
+			return nil
+		} else if len(distributed) > 0 {
+			fmt.Print(colorOrange)
+			fmt.Println("The bundle was not included, but some minipools have been distributed in the meantime:")
+			for _, minipool := range distributed {
+				fmt.Printf("    %s\n", minipool.Hex())
+			}
+			fmt.Println("Remove them from the list before trying again.")
+			fmt.Print(colorReset, "\n")
+		}
+	}
+
 	if !successfullyIncluded {
 		fmt.Println(string(colorRed), "Error: Bundle was not included in the mempool.", string(colorReset))
 		fmt.Println("This can happen at times of high activity. Please try again later.")
@@ -210,6 +810,10 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 		return nil
 	}
 
+	report.Included = true
+	dataIn.session.valueAtRisk.Add(dataIn.session.valueAtRisk, valueAtRisk)
+	dataIn.session.ethSpent.Add(dataIn.session.ethSpent, ethSpend)
+
 	// print successful inclusion and tx link
 	var txType string
 	if dataIn.LocalReth {
@@ -218,31 +822,395 @@ func ExecuteDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn)
 		txType = "Arbitrage"
 	}
 
-	explorer := ""
-	if dataIn.NetworkId == 1 {
-		explorer = "https://etherscan.io/tx/"
-	} else if dataIn.NetworkId == 17000 {
-		explorer = "https://explorer.holesky.io/tx/"
+	for _, tx := range bundle.Transactions() {
+		includedTx := IncludedTx{Type: "Distribute", Hash: tx.Hash()}
+		if tx == result.ArbitrageTx {
+			includedTx.Type = txType
+		}
+		if tx == result.DonationTx {
+			includedTx.Type = "Donation"
+		}
+		report.Txs = append(report.Txs, includedTx)
 	}
-	if len(bundle.Transactions()) == 2 {
-		fmt.Printf("Distributed minipool! %s tx: %s%s\n\n", txType, explorer, arbTxHash.Hex())
+
+	if len(result.IncludedMinipools) == 1 {
+		fmt.Printf("Distributed minipool! %s tx: %s\n", txType, explorerTxUrl(dataIn, arbTxHash))
 	} else {
-		fmt.Printf("Distributed minipools! %s tx: %s%s\n\n", txType, explorer, arbTxHash.Hex())
+		fmt.Printf("Distributed minipools! %s tx: %s\n", txType, explorerTxUrl(dataIn, arbTxHash))
+	}
+	for _, includedTx := range report.Txs {
+		fmt.Printf("    %s tx: %s\n", includedTx.Type, explorerTxUrl(dataIn, includedTx.Hash))
+	}
+	if report.InclusionBlock > 0 {
+		fmt.Printf("Landed in block %d with %d confirmations.\n", report.InclusionBlock, dataIn.InclusionConfirmations)
 	}
 
+	// burning own rETH has no profit to book
+	if dataIn.Ledger != "" && !dataIn.LocalReth {
+		row, err := newLedgerRow(ctx, dataIn, bundle, arbTxHash, bundleHash, len(result.IncludedMinipools), expectedProfit)
+		if err == nil {
+			err = appendLedger(dataIn.Ledger, &row)
+		}
+		if err != nil {
+			logger.Warn("failed to write ledger", slog.String("file", dataIn.Ledger), slog.String("error", err.Error()))
+		} else {
+			fmt.Printf("Realized profit: %s, net after the fees paid: %s, cumulative: %s\n",
+				formatEth(weiToEth(row.RealizedProfit), dataIn.Precision, dataIn.Scientific),
+				formatEth(weiToEth(row.NetProfit), dataIn.Precision, dataIn.Scientific),
+				formatEth(weiToEth(row.CumulativeProfit), dataIn.Precision, dataIn.Scientific),
+			)
+		}
+	}
+
+	if dataIn.Confirmations > 0 {
+		fmt.Printf("Waiting for %d confirmations...\n", dataIn.Confirmations)
+		confirmedBlock, err := waitForConfirmations(ctx, dataIn, arbTxHash, dataIn.Confirmations, dataIn.ConfirmationTimeout)
+		if err != nil {
+			return errors.Join(errors.New("failed to confirm the inclusion"), err)
+		}
+		report.ConfirmedBlock = confirmedBlock
+		fmt.Printf("Confirmed in block %d with %d confirmations.\n", confirmedBlock, dataIn.Confirmations)
+	}
+
+	// informational only, the bundle is already included
+	builder, err := getBuilderInfo(ctx, dataIn, arbTxHash)
+	if err != nil {
+		logger.Warn("failed to get builder of the including block", slog.String("error", err.Error()))
+		fmt.Println()
+		return nil
+	}
+	report.Builder = builder
+	fmt.Printf("Included in block %d by builder %s (fee recipient %s)\n\n", builder.Block, builder.Name, builder.FeeRecipient.Hex())
+
 	return nil
 }
 
-func evalGasPrices(bundle *flashbots_client.Bundle) (bundleGasPrice, arbitrageGasPrice *big.Int) {
+// reportNothingToDistribute ends the run cleanly, e.g. all minipools were left out by --gas-budget or --top.
+// With --print-profit-only there is no profit to gate on, so it stays an error there.
+func reportNothingToDistribute(dataIn *DataIn, report *RunReport) error {
+	if dataIn.ProfitOutput != nil {
+		return ErrNothingToDistribute
+	}
+
+	report.NothingToDistribute = true
+	fmt.Println("No distributable minipools found, nothing to do.")
+	return nil
+}
+
+// explorerTxUrl links the tx on the block explorer of the network, or on the one given with --explorer-url
+func explorerTxUrl(dataIn *DataIn, hash common.Hash) string {
+	explorer := dataIn.ExplorerUrl
+	if explorer == "" {
+		if dataIn.NetworkId == 1 {
+			explorer = "https://etherscan.io"
+		} else if dataIn.NetworkId == 17000 {
+			explorer = "https://explorer.holesky.io"
+		}
+	}
+
+	return explorer + "/tx/" + hash.Hex()
+}
+
+// guardDuplicateSubmission refuses the submission if it is a duplicate, otherwise records it as pending
+func guardDuplicateSubmission(logger *slog.Logger, dataIn *DataIn, minipools []common.Address, targetBlock uint64) (*submission, error) {
+	submissions, err := loadSubmissions(dataIn.SubmissionLog)
+	if err != nil {
+		return nil, err
+	}
+
+	key := submissionKey(minipools)
+	err = checkDuplicateSubmission(submissions, key, targetBlock, dataIn.DedupeWindow, time.Now())
+	if err != nil {
+		logger.Warn("submission suppressed",
+			slog.String("key", key.Hex()),
+			slog.Uint64("targetBlock", targetBlock),
+			slog.String("reason", err.Error()),
+		)
+		return nil, err
+	}
+
+	entry := &submission{
+		Key:         key,
+		TargetBlock: targetBlock,
+		SubmittedAt: time.Now(),
+		Status:      submissionPending,
+	}
+
+	err = recordSubmission(dataIn.SubmissionLog, *entry, dataIn.DedupeWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func evalGasPrices(bundle *flashbots_client.Bundle, arbitrageTx *types.Transaction) (bundleGasPrice, arbitrageGasPrice *big.Int) {
 	bundleGasPrice = bundle.MaximumGasFeePaid()
 
-	txs := bundle.Transactions()
-	arbTx := txs[len(txs)-1]
+	return bundleGasPrice, arbitrageTx.Cost()
+}
 
-	return bundleGasPrice, arbTx.Cost()
+// expectedFeeRefund models the flashbots gas fee refund as refundPct of the max priority fees of the bundle.
+// The real refund depends on the builder's profit from the block, so this is an estimate for the summary only.
+func expectedFeeRefund(bundle *flashbots_client.Bundle, refundPct float64) *big.Int {
+	priorityFees := new(big.Int)
+	for _, tx := range bundle.Transactions() {
+		priorityFees.Add(priorityFees, new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), tx.GasTipCap()))
+	}
+
+	// percent with two decimals
+	refund := new(big.Int).Mul(priorityFees, big.NewInt(int64(refundPct*100)))
+	return refund.Div(refund, big.NewInt(10000))
 }
 
-func waitForUserConfirmation(isUsingLocalReth bool) bool {
+// txIndex returns the position of the tx with the given hash in the bundle
+func txIndex(bundle *flashbots_client.Bundle, hash common.Hash) (int, error) {
+	for i, tx := range bundle.Transactions() {
+		if tx.Hash() == hash {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("tx %s is not part of the bundle", hash.Hex())
+}
+
+// checkProfit verifies the expected profit covers the fees of the bundle, or only the arbitrage tx
+// if the distribute cost should be ignored. With CheckProfitBoth both conditions are checked and every
+// failing one is reported.
+func checkProfit(dataIn *DataIn, expectedProfit, maxBundleFees, maxArbitrageFees *big.Int) error {
+	if !dataIn.CheckProfit {
+		return nil
+	}
+
+	// the floor is on the net of the checked mode, only ignoring the distribute cost nets the arbitrage fees alone
+	net := new(big.Int).Sub(expectedProfit, maxBundleFees)
+	if dataIn.CheckProfitIgnoreDistributeCost {
+		net = new(big.Int).Sub(expectedProfit, maxArbitrageFees)
+	}
+	floorErr := checkMinAbsoluteProfit(dataIn, net)
+
+	if dataIn.CheckProfitBoth {
+		errs := []error{floorErr}
+		if expectedProfit.Cmp(maxArbitrageFees) < 0 {
+			errs = append(errs, fmt.Errorf("expected profit of %.6f ETH does not cover the max arbitrage fees of %.6f ETH", weiToEth(expectedProfit), weiToEth(maxArbitrageFees)))
+		}
+		if expectedProfit.Cmp(maxBundleFees) < 0 {
+			errs = append(errs, fmt.Errorf("net profit after the max bundle fees is negative: %.6f ETH", weiToEth(new(big.Int).Sub(expectedProfit, maxBundleFees))))
+		}
+		return errors.Join(errs...)
+	}
+
+	// this checks if a bundle makes sense to make arbitrage profits
+	if !dataIn.CheckProfitIgnoreDistributeCost && expectedProfit.Cmp(maxBundleFees) < 0 {
+		return errors.New("expected profit is less than max bundle fees")
+	}
+
+	// this checks if a bundle makes sense if the user wants to distribute
+	// aka. distribute gas needs to be paid one way or another
+	if dataIn.CheckProfitIgnoreDistributeCost && expectedProfit.Cmp(maxArbitrageFees) < 0 {
+		return errors.New("expected profit is less than max arbitrage fees")
+	}
+
+	return floorErr
+}
+
+func checkMinAbsoluteProfit(dataIn *DataIn, net *big.Int) error {
+	if dataIn.MinAbsoluteProfit == nil || net.Cmp(dataIn.MinAbsoluteProfit) >= 0 {
+		return nil
+	}
+
+	return fmt.Errorf("net profit of %.6f ETH is below the minimum absolute profit of %.6f ETH, short by %.6f ETH",
+		weiToEth(net),
+		weiToEth(dataIn.MinAbsoluteProfit),
+		weiToEth(new(big.Int).Sub(dataIn.MinAbsoluteProfit, net)),
+	)
+}
+
+// discountPercent expresses an amount of ETH as a discount on the ETH sent to the rETH contract.
+// For the bundle fees this is the minimum rETH discount at which the arbitrage is still net-positive,
+// for the expected profit it is the discount the swap currently captures.
+func discountPercent(amount, rethShare *big.Int) float64 {
+	if rethShare == nil || rethShare.Sign() == 0 {
+		return 0
+	}
+
+	ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(rethShare)).Float64()
+	return ratio * 100
+}
+
+func printUsdValues(price *ChainlinkPrice, profit, fees float64) {
+	if price == nil {
+		return
+	}
+
+	fmt.Printf("    Expected profit after fees: $%.2f, with a tx fee of $%.2f (ETH/USD %.2f from chainlink feed %s, updated %s)\n",
+		profit*price.Price,
+		fees*price.Price,
+		price.Price,
+		price.Feed.Hex(),
+		price.UpdatedAt.UTC().Format(time.RFC3339),
+	)
+}
+
+func printExchangeRateSource(source *ExchangeRateSource) {
+	if source == nil {
+		return
+	}
+
+	rateFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(source.Rate), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+	fmt.Printf("    rETH exchange rate: %.6f ETH per rETH (%s on %s at block %d)\n", rateFloat, source.Method, source.Contract.Hex(), source.Block)
+}
+
+func printPoolPriceChange(change *PoolPriceChange) {
+	if change == nil {
+		return
+	}
+
+	fmt.Printf("    Pool price: %.6f -> %.6f WETH per rETH, discount to the protocol rate %.4f%% -> %.4f%%\n",
+		change.Before,
+		change.After,
+		change.DiscountBefore,
+		change.DiscountAfter,
+	)
+}
+
+// printRelaySimulation shows the fees as seen by the relay next to the computed ones. The profit goes to the receiver,
+// which the relay does not report, so the cross-check is on the fee side: the relay fees must stay below the max fee
+// and what the builder gets must stay below the expected profit.
+func printRelaySimulation(dataIn *DataIn, res *flashbots_client.SimulationResultBundle, expectedProfit, maxBundleFees *big.Int) {
+	// an rpc simulation with state overrides has no relay view
+	if res == nil || res.CoinbaseDiff == nil || res.GasFees == nil {
+		return
+	}
+
+	fmt.Printf("    Relay simulation: gas fees %s, coinbase diff %s, sent to coinbase %s (computed max fee %s)\n",
+		formatEth(weiToEth(res.GasFees), dataIn.Precision, dataIn.Scientific),
+		formatEth(weiToEth(res.CoinbaseDiff), dataIn.Precision, dataIn.Scientific),
+		formatEth(weiToEth(res.EthSentToCoinbase), dataIn.Precision, dataIn.Scientific),
+		formatEth(weiToEth(maxBundleFees), dataIn.Precision, dataIn.Scientific),
+	)
+
+	if res.GasFees.Cmp(maxBundleFees) > 0 {
+		fmt.Print(colorOrange, "    The relay reports higher fees than the computed max fee, the profit after fees is overestimated.", colorReset, "\n")
+	}
+	if expectedProfit != nil && res.CoinbaseDiff.Cmp(expectedProfit) >= 0 {
+		fmt.Print(colorRed, "    The builder receives more than the expected profit, the bundle would run at a loss.", colorReset, "\n")
+	}
+}
+
+// printGasUtilization compares the gas used in the simulation with the sum of the gas limits of the bundle.
+// The max fee is paid on the limits, a low utilization reserves more than needed.
+func printGasUtilization(bundle *flashbots_client.Bundle, simulatedGas []uint64) {
+	reserved := uint64(0)
+	for _, tx := range bundle.Transactions() {
+		reserved += tx.Gas()
+	}
+	used := uint64(0)
+	for _, gas := range simulatedGas {
+		used += gas
+	}
+	if reserved == 0 {
+		return
+	}
+
+	utilization := float64(used) / float64(reserved)
+	fmt.Printf("    Gas used: %d of %d reserved (utilization %.2f)\n", used, reserved, utilization)
+	if utilization > GAS_UTILIZATION_WARN {
+		fmt.Print(colorOrange, "    The gas limits leave little headroom, consider a higher --gas-buffer-pct.", colorReset, "\n")
+	}
+}
+
+func printInclusionEstimate(inclusionBlocks uint64, err error) {
+	switch {
+	case err != nil:
+		fmt.Print("    Estimated inclusion: unknown\n\n")
+	case inclusionBlocks == 0:
+		fmt.Printf("    Estimated inclusion: not within the last %d blocks at current tip, consider bumping the tip\n\n", INCLUSION_ESTIMATE_BLOCKS)
+	default:
+		fmt.Printf("    Estimated inclusion within ~%d blocks at current tip\n\n", inclusionBlocks)
+	}
+}
+
+// basisPoints expresses amount in basis points of total
+func basisPoints(amount, total *big.Int) float64 {
+	if total == nil || total.Sign() == 0 {
+		return 0
+	}
+
+	ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(total)).Float64()
+	return ratio * 10000
+}
+
+// formatEth prints an amount with the given number of decimals. With scientific set, non-zero amounts
+// that would be rounded to zero are printed in scientific notation instead.
+func formatEth(amount float64, precision int, scientific bool) string {
+	if scientific && amount != 0 && math.Abs(amount) < math.Pow10(-precision) {
+		return strconv.FormatFloat(amount, 'e', max(precision, 1), 64)
+	}
+
+	return strconv.FormatFloat(amount, 'f', precision, 64)
+}
+
+func weiToEth(amount *big.Int) float64 {
+	if amount == nil {
+		return 0
+	}
+
+	amountFloat, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+	return amountFloat
+}
+
+// getTotalBalance returns the combined balance of the minipools, which is the amount distributed (NO and rETH share)
+func getTotalBalance(ctx context.Context, dataIn *DataIn, minipools []common.Address) (*big.Int, error) {
+	total := new(big.Int)
+	for _, minipool := range minipools {
+		balance, err := dataIn.Client.BalanceAt(ctx, minipool, nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool balance", minipool), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		total.Add(total, balance)
+	}
+
+	return total, nil
+}
+
+// printConfirmationSummary reprints the figures the confirmation is based on right above the prompt
+func printConfirmationSummary(ctx context.Context, logger *slog.Logger, dataIn *DataIn, result *BuildResult, expectedProfit, maxBundleFees *big.Int) {
+	if !logger.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	fmt.Println("Summary:")
+	fmt.Printf("    Minipools: %d\n", len(result.IncludedMinipools))
+	if dataIn.LocalReth {
+		fmt.Printf("    rETH to burn: %s for %s ETH\n",
+			formatEth(weiToEth(result.RethToBurn), dataIn.Precision, dataIn.Scientific),
+			formatEth(weiToEth(result.RethShare), dataIn.Precision, dataIn.Scientific),
+		)
+	} else {
+		fmt.Printf("    Expected profit after fees: %s\n", formatEth(weiToEth(new(big.Int).Sub(expectedProfit, maxBundleFees)), dataIn.Precision, dataIn.Scientific))
+		fmt.Printf("    rETH discount: %.4f%%\n", discountPercent(expectedProfit, result.RethShare))
+	}
+	fmt.Printf("    Max fee: %s\n", formatEth(weiToEth(maxBundleFees), dataIn.Precision, dataIn.Scientific))
+
+	// the target block is set once confirmed, this is the block it would be right now
+	blockNumber, err := dataIn.Client.BlockNumber(ctx)
+	if err != nil {
+		logger.Warn("failed to get block number for the summary", slog.String("error", err.Error()))
+	} else {
+		fmt.Printf("    Target block: %d\n", blockNumber+1)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+	fmt.Println()
+}
+
+func waitForUserConfirmation(isUsingLocalReth bool, requiredAmount string) bool {
 	if isUsingLocalReth {
 		fmt.Println(string(colorRed), "\nSince you're using your own rETH, this transaction is NOT time-sensitive.")
 		fmt.Println("Feel free to review and confirm the transactions above at your own pace. For instance by using Tenderly.")
@@ -252,12 +1220,29 @@ func waitForUserConfirmation(isUsingLocalReth bool) bool {
 	}
 
 	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Do you want to proceed? (y/n): ")
+	if requiredAmount != "" {
+		fmt.Printf("This run distributes %s ETH to rETH. Type the amount to proceed or 'n' to abort: ", requiredAmount)
+	} else {
+		fmt.Print("Do you want to proceed? (y/n): ")
+	}
 	response, err := reader.ReadString('\n')
 	if err != nil {
 		log.Fatal(err)
 	}
 	response = strings.TrimSpace(response)
+
+	if requiredAmount != "" {
+		switch strings.ToLower(response) {
+		case requiredAmount:
+			return true
+		case "n", "no":
+			return false
+		default:
+			fmt.Printf("Invalid input. Please type '%s' or 'n'.\n", requiredAmount)
+			return waitForUserConfirmation(false, requiredAmount)
+		}
+	}
+
 	switch strings.ToLower(response) {
 	case "y", "yes":
 		return true
@@ -265,7 +1250,7 @@ func waitForUserConfirmation(isUsingLocalReth bool) bool {
 		return false
 	default:
 		fmt.Println("Invalid input. Please type 'y' or 'n'.")
-		return waitForUserConfirmation(isUsingLocalReth)
+		return waitForUserConfirmation(isUsingLocalReth, requiredAmount)
 	}
 }
 
@@ -299,24 +1284,87 @@ func getWithdrawalAddress(ctx context.Context, client *ethclient.Client, network
 	return address, nil
 }
 
-func simulateBundle(logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle) (bool, common.Hash, common.Hash, error) {
+// nodeEthSpend sums the value and the max fee of the bundle txs sent from the node address
+func nodeEthSpend(dataIn *DataIn, bundle *flashbots_client.Bundle) (*big.Int, error) {
+	spend := new(big.Int)
+	for _, tx := range bundle.Transactions() {
+		sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to recover sender", tx.Hash().Hex()), err)
+		}
+		if dataIn.NodeAddress == nil || sender != *dataIn.NodeAddress {
+			continue
+		}
+
+		spend.Add(spend, tx.Cost())
+	}
+
+	return spend, nil
+}
+
+// emitDryRunSendBundle emits the requests that would be sent, targeting the next block like a real submission
+func emitDryRunSendBundle(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle) error {
+	blockNumber, err := dataIn.Client.BlockNumber(ctx)
+	if err != nil {
+		return errors.Join(errors.New("failed to get block number"), err)
+	}
+
+	wouldSend := bundle.Copy()
+	if !dataIn.NoBroadcast {
+		wouldSend.UseAllBuilders(dataIn.NetworkId)
+	}
+	err = wouldSend.SetTargetBlockNumber(blockNumber + 1)
+	if err != nil {
+		return errors.Join(errors.New("failed to set target block"), err)
+	}
+
+	validBlocks := dataIn.ValidBlocks
+	if validBlocks == 0 {
+		validBlocks = DEFAULT_VALID_BLOCKS
+	}
+
+	return emitSendBundle(dataIn.EmitSendBundle, wouldSend, validBlocks)
+}
+
+// simulateBundle simulates the bundle and reports reverting txs. The simulation result is returned as well,
+// it holds the relay's own view of the fees.
+func simulateBundle(logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, arbitrageTx *types.Transaction, rethShare *big.Int) (bool, common.Hash, common.Hash, []uint64, *flashbots_client.SimulationResultBundle, error) {
+	arbitrageIndex, err := txIndex(bundle, arbitrageTx.Hash())
+	if err != nil {
+		return false, common.Hash{}, common.Hash{}, nil, nil, err
+	}
+
 	simulationStateBlock, err := dataIn.Client.BlockNumber(context.Background())
 	if err != nil {
-		return false, common.Hash{}, common.Hash{}, errors.Join(errors.New("failed to get block number"), err)
+		return false, common.Hash{}, common.Hash{}, nil, nil, errors.Join(errors.New("failed to get block number"), err)
 	}
 
-	res, success, err := dataIn.FbClient.SimulateBundle(bundle, simulationStateBlock)
+	res, success, err := simulateAtOffset(logger, dataIn, bundle, simulationStateBlock)
 	// If there was an error and it's the special "header not found" error, try with previous block
 	if err != nil && strings.Contains(err.Error(), "header not found") {
 		logger.Debug("header not found, retrying", slog.Uint64("previous block", simulationStateBlock-1))
-		res, success, err = dataIn.FbClient.SimulateBundle(bundle, simulationStateBlock-1)
+		res, success, err = simulateAtOffset(logger, dataIn, bundle, simulationStateBlock-1)
 	}
 	if err != nil {
-		return false, common.Hash{}, common.Hash{}, err
+		return false, common.Hash{}, common.Hash{}, nil, nil, err
+	}
+
+	if dataIn.SimulationDetails {
+		printSimulationDetails(bundle, res)
 	}
 
 	for index, tx := range res.Results {
 		if tx.Error != "" {
+			if dataIn.DistributePriority && tx.TxHash == arbitrageTx.Hash() {
+				fmt.Print(colorOrange, "\nWarning: the arbitrage tx reverts in the simulation (", sanitizeString(tx.RevertReason), "). Distributing without arbitrage profit.", colorReset, "\n")
+				continue
+			}
+
+			if dataIn.Trace {
+				// only the txs from the arbitrage on depend on the ETH sent by the distribute calls
+				printTrace(logger, dataIn, bundle, index, index >= arbitrageIndex, rethShare)
+			}
+
 			parsedMsg := sanitizeString(tx.RevertReason)
 			// handle known revert reasons
 			if strings.EqualFold(parsedMsg, "Paraswap failed") || strings.EqualFold(parsedMsg, "Insufficient ETH balance for exchange") {
@@ -324,7 +1372,7 @@ func simulateBundle(logger *slog.Logger, dataIn *DataIn, bundle *flashbots_clien
 				fmt.Println("This issue is often caused by significant price movements or high MEV bot activity.")
 				fmt.Println("Please try again shortly.")
 
-				return false, common.Hash{}, common.Hash{}, errors.New(parsedMsg)
+				return false, common.Hash{}, common.Hash{}, nil, nil, errors.New(parsedMsg)
 			}
 
 			logger.Warn("tx failed",
@@ -338,7 +1386,97 @@ func simulateBundle(logger *slog.Logger, dataIn *DataIn, bundle *flashbots_clien
 		}
 	}
 
-	return success, res.BundleHash, res.Results[len(res.Results)-1].TxHash, nil
+	simulatedGas := make([]uint64, len(res.Results))
+	for index, tx := range res.Results {
+		simulatedGas[index] = tx.GasUsed
+	}
+
+	for _, tx := range res.Results {
+		if tx.TxHash == arbitrageTx.Hash() {
+			return success, res.BundleHash, tx.TxHash, simulatedGas, res, nil
+		}
+	}
+
+	return false, common.Hash{}, common.Hash{}, nil, nil, errors.New("simulation result is missing the arbitrage tx")
+}
+
+// simulateAtOffset simulates the bundle on top of the state block as part of the block SimulationOffset blocks later,
+// so the base fee and block number match where the bundle will land. An offset of 0 simulates in the state block itself.
+func simulateAtOffset(logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, stateBlock uint64) (*flashbots_client.SimulationResultBundle, bool, error) {
+	// the target of the bundle itself is only set right before sending
+	simulation := bundle.Copy()
+	if dataIn.SimulationOffset > 0 {
+		err := simulation.SetTargetBlockNumber(stateBlock + dataIn.SimulationOffset)
+		if err != nil {
+			return nil, false, errors.Join(errors.New("failed to set simulation block"), err)
+		}
+	}
+	logger.Debug("simulating bundle", slog.Uint64("stateBlock", stateBlock), slog.Uint64("offset", dataIn.SimulationOffset))
+
+	if dataIn.StateOverride != nil {
+		logger.Debug("simulating with state overrides on the rpc", slog.Int("accounts", len(dataIn.StateOverride)))
+		return simulateWithStateOverride(dataIn, simulation, stateBlock)
+	}
+
+	return dataIn.FbClient.SimulateBundle(simulation, stateBlock)
+}
+
+// printSimulationDetails prints one row per simulated tx, the gas limit is taken from the bundle
+func printSimulationDetails(bundle *flashbots_client.Bundle, res *flashbots_client.SimulationResultBundle) {
+	txs := bundle.Transactions()
+
+	fmt.Println("\nSimulation results:")
+	fmt.Printf("    %-5s %-42s %10s %10s %6s %-8s %20s %s\n", "Index", "To", "Gas Used", "Gas Limit", "Used", "Status", "Value", "Coinbase Diff")
+	for index, tx := range res.Results {
+		status := "success"
+		if tx.Error != "" {
+			status = "reverted"
+		}
+
+		gasLimit := uint64(0)
+		value := "0"
+		if index < len(txs) {
+			gasLimit = txs[index].Gas()
+			value = txs[index].Value().String()
+		}
+
+		usedPct := 0.0
+		if gasLimit > 0 {
+			usedPct = float64(tx.GasUsed) / float64(gasLimit) * 100
+		}
+
+		// the builder is paid the gas fees and any direct transfer to the coinbase
+		coinbaseDiff := "-"
+		if tx.CoinbaseDiff != nil {
+			coinbaseDiff = tx.CoinbaseDiff.String()
+			if tx.EthSentToCoinbase != nil && tx.EthSentToCoinbase.Sign() > 0 {
+				coinbaseDiff += " (" + tx.EthSentToCoinbase.String() + " sent)"
+			}
+		}
+
+		fmt.Printf("    %-5d %-42s %10d %10d %5.1f%% %-8s %20s %s\n", index+1, tx.ToAddress.Hex(), tx.GasUsed, gasLimit, usedPct, status, value, coinbaseDiff)
+	}
+	fmt.Printf("    Total gas used: %d\n", res.TotalGasUsed)
+	if res.CoinbaseDiff != nil {
+		fmt.Printf("    Total coinbase diff: %s wei\n", res.CoinbaseDiff.String())
+	}
+	fmt.Println()
+}
+
+// printTrace prints the call trace of a failed bundle tx, errors are only logged
+func printTrace(logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, index int, overrideRethBalance bool, rethShare *big.Int) {
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	frame, err := traceBundleTransaction(timeoutCtx, dataIn, bundle, index, overrideRethBalance, rethShare)
+	if err != nil {
+		logger.Warn("failed to trace tx", slog.Int("index", index), slog.String("error", err.Error()))
+		return
+	}
+
+	fmt.Printf("\nCall trace of failed transaction %d:\n", index+1)
+	printCallFrame(frame, 0)
+	fmt.Println()
 }
 
 // best effort to sanitize revert reasons
@@ -0,0 +1,405 @@
+package arbitrage
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func Test_checkProfit(t *testing.T) {
+	eth := func(milli int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(milli), big.NewInt(1e15))
+	}
+
+	type args struct {
+		dataIn           *DataIn
+		expectedProfit   *big.Int
+		maxBundleFees    *big.Int
+		maxArbitrageFees *big.Int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "profit covers bundle fees",
+			args: args{
+				dataIn:           &DataIn{CheckProfit: true},
+				expectedProfit:   eth(20),
+				maxBundleFees:    eth(10),
+				maxArbitrageFees: eth(5),
+			},
+			wantErr: false,
+		},
+		{
+			name: "profit below bundle fees",
+			args: args{
+				dataIn:           &DataIn{CheckProfit: true},
+				expectedProfit:   eth(8),
+				maxBundleFees:    eth(10),
+				maxArbitrageFees: eth(5),
+			},
+			wantErr: true,
+		},
+		{
+			name: "min absolute profit met",
+			args: args{
+				dataIn:           &DataIn{CheckProfit: true, MinAbsoluteProfit: eth(10)},
+				expectedProfit:   eth(20),
+				maxBundleFees:    eth(10),
+				maxArbitrageFees: eth(5),
+			},
+			wantErr: false,
+		},
+		{
+			name: "profit covers bundle fees, below min absolute profit",
+			args: args{
+				dataIn:           &DataIn{CheckProfit: true, MinAbsoluteProfit: eth(11)},
+				expectedProfit:   eth(20),
+				maxBundleFees:    eth(10),
+				maxArbitrageFees: eth(5),
+			},
+			wantErr: true,
+		},
+		{
+			name: "ignore distribute cost, covers arbitrage fees, below min absolute profit",
+			args: args{
+				dataIn:           &DataIn{CheckProfit: true, CheckProfitIgnoreDistributeCost: true, MinAbsoluteProfit: eth(5)},
+				expectedProfit:   eth(8),
+				maxBundleFees:    eth(10),
+				maxArbitrageFees: eth(5),
+			},
+			wantErr: true,
+		},
+		{
+			name: "check profit disabled",
+			args: args{
+				dataIn:           &DataIn{CheckProfit: false},
+				expectedProfit:   eth(0),
+				maxBundleFees:    eth(10),
+				maxArbitrageFees: eth(5),
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkProfit(tt.args.dataIn, tt.args.expectedProfit, tt.args.maxBundleFees, tt.args.maxArbitrageFees); (err != nil) != tt.wantErr {
+				t.Errorf("checkProfit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkProfitBoth(t *testing.T) {
+	eth := func(milli int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(milli), big.NewInt(1e15))
+	}
+
+	// the expected profit is always 10 mETH
+	tests := []struct {
+		name             string
+		maxArbitrageFees *big.Int
+		maxBundleFees    *big.Int
+		coversArbitrage  bool
+		coversBundle     bool
+	}{
+		{
+			name:             "covers arbitrage and bundle fees",
+			maxArbitrageFees: eth(5),
+			maxBundleFees:    eth(8),
+			coversArbitrage:  true,
+			coversBundle:     true,
+		},
+		{
+			name:             "covers arbitrage but not bundle fees",
+			maxArbitrageFees: eth(5),
+			maxBundleFees:    eth(12),
+			coversArbitrage:  true,
+			coversBundle:     false,
+		},
+		{
+			// not possible with real fees, the bundle contains the arbitrage tx
+			name:             "covers bundle but not arbitrage fees",
+			maxArbitrageFees: eth(12),
+			maxBundleFees:    eth(8),
+			coversArbitrage:  false,
+			coversBundle:     true,
+		},
+		{
+			name:             "covers neither",
+			maxArbitrageFees: eth(12),
+			maxBundleFees:    eth(15),
+			coversArbitrage:  false,
+			coversBundle:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataIn := &DataIn{CheckProfit: true, CheckProfitBoth: true}
+			err := checkProfit(dataIn, eth(10), tt.maxBundleFees, tt.maxArbitrageFees)
+
+			wantErr := !tt.coversArbitrage || !tt.coversBundle
+			if (err != nil) != wantErr {
+				t.Fatalf("checkProfit() error = %v, wantErr %v", err, wantErr)
+			}
+			if err == nil {
+				return
+			}
+
+			if reported := strings.Contains(err.Error(), "arbitrage fees"); reported == tt.coversArbitrage {
+				t.Errorf("checkProfit() error = %v, arbitrage fee condition reported: %v", err, reported)
+			}
+			if reported := strings.Contains(err.Error(), "bundle fees"); reported == tt.coversBundle {
+				t.Errorf("checkProfit() error = %v, bundle fee condition reported: %v", err, reported)
+			}
+		})
+	}
+}
+
+func newTestTx(nonce, gas uint64) *types.Transaction {
+	to := common.HexToAddress("0xfA82e08c42E6F62f95623F9ee8f2b15716F02aA6")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(10e9),
+		Gas:       gas,
+		To:        &to,
+		Value:     big.NewInt(0),
+	})
+}
+
+func Test_evalGasPrices(t *testing.T) {
+	distributeTx := newTestTx(0, DISTRIBUTE_CALL_MAX_GAS)
+	arbitrageTx := newTestTx(1, ARBITRAGE_UNISWAP_CALL_MAX_GAS)
+	trailingTx := newTestTx(2, 21000)
+
+	tests := []struct {
+		name              string
+		txs               []*types.Transaction
+		wantArbitrageCost *big.Int
+	}{
+		{
+			name:              "arbitrage tx last",
+			txs:               []*types.Transaction{distributeTx, arbitrageTx},
+			wantArbitrageCost: arbitrageTx.Cost(),
+		},
+		{
+			name:              "trailing non-arbitrage tx",
+			txs:               []*types.Transaction{distributeTx, arbitrageTx, trailingTx},
+			wantArbitrageCost: arbitrageTx.Cost(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := flashbots_client.NewBundleWithTransactions(tt.txs)
+			bundleFees, arbitrageFees := evalGasPrices(bundle, arbitrageTx)
+
+			if arbitrageFees.Cmp(tt.wantArbitrageCost) != 0 {
+				t.Errorf("evalGasPrices() arbitrage fees = %v, want %v", arbitrageFees, tt.wantArbitrageCost)
+			}
+			if bundleFees.Cmp(bundle.MaximumGasFeePaid()) != 0 {
+				t.Errorf("evalGasPrices() bundle fees = %v, want %v", bundleFees, bundle.MaximumGasFeePaid())
+			}
+		})
+	}
+}
+
+func Test_txIndex(t *testing.T) {
+	distributeTx := newTestTx(0, DISTRIBUTE_CALL_MAX_GAS)
+	arbitrageTx := newTestTx(1, ARBITRAGE_UNISWAP_CALL_MAX_GAS)
+	trailingTx := newTestTx(2, 21000)
+	bundle := flashbots_client.NewBundleWithTransactions([]*types.Transaction{distributeTx, arbitrageTx, trailingTx})
+
+	tests := []struct {
+		name    string
+		hash    common.Hash
+		want    int
+		wantErr bool
+	}{
+		{name: "arbitrage tx followed by another tx", hash: arbitrageTx.Hash(), want: 1},
+		{name: "trailing tx", hash: trailingTx.Hash(), want: 2},
+		{name: "unknown tx", hash: common.Hash{1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := txIndex(bundle, tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("txIndex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("txIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatEth(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     float64
+		precision  int
+		scientific bool
+		want       string
+	}{
+		{name: "default precision", amount: 0.0123456789, precision: 6, want: "0.012346"},
+		{name: "tiny amount rounded", amount: 0.0000001234, precision: 6, want: "0.000000"},
+		{name: "tiny amount scientific", amount: 0.0000001234, precision: 6, scientific: true, want: "1.234000e-07"},
+		{name: "negative tiny amount scientific", amount: -0.0000001234, precision: 2, scientific: true, want: "-1.23e-07"},
+		{name: "representable amount not scientific", amount: 1.5, precision: 2, scientific: true, want: "1.50"},
+		{name: "zero not scientific", amount: 0, precision: 2, scientific: true, want: "0.00"},
+		{name: "zero precision", amount: 12.7, precision: 0, want: "13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatEth(tt.amount, tt.precision, tt.scientific); got != tt.want {
+				t.Errorf("formatEth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_expectedFeeRefund(t *testing.T) {
+	// 1 gwei tip per gas
+	bundle := flashbots_client.NewBundleWithTransactions([]*types.Transaction{
+		newTestTx(0, DISTRIBUTE_CALL_MAX_GAS),
+		newTestTx(1, ARBITRAGE_UNISWAP_CALL_MAX_GAS),
+	})
+	priorityFees := int64((DISTRIBUTE_CALL_MAX_GAS + ARBITRAGE_UNISWAP_CALL_MAX_GAS) * 1e9)
+
+	tests := []struct {
+		name      string
+		refundPct float64
+		want      *big.Int
+	}{
+		{name: "no refund", refundPct: 0, want: big.NewInt(0)},
+		{name: "full refund", refundPct: 100, want: big.NewInt(priorityFees)},
+		{name: "90 percent", refundPct: 90, want: big.NewInt(priorityFees * 9 / 10)},
+		{name: "fractional percent", refundPct: 12.5, want: big.NewInt(priorityFees / 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expectedFeeRefund(bundle, tt.refundPct); got.Cmp(tt.want) != 0 {
+				t.Errorf("expectedFeeRefund() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_scoreMinipools(t *testing.T) {
+	small := common.HexToAddress("0x1")
+	large := common.HexToAddress("0x2")
+	empty := common.HexToAddress("0x3")
+	shares := []MinipoolShare{
+		{Address: small, RethShare: big.NewInt(8e18)},
+		{Address: empty, RethShare: big.NewInt(0)},
+		{Address: large, RethShare: new(big.Int).Mul(big.NewInt(24), big.NewInt(1e18))},
+	}
+
+	gasLimits := map[common.Address]uint64{
+		small: 100000,
+		large: DISTRIBUTE_CALL_MAX_GAS,
+		empty: 100000,
+	}
+
+	// 1% discount, 10 gwei gas, the small minipool has less profit but needs far less gas
+	scores := scoreMinipools(shares, 0.01, big.NewInt(10e9), gasLimits)
+
+	wantOrder := []common.Address{small, large, empty}
+	for i, want := range wantOrder {
+		if scores[i].Address != want {
+			t.Errorf("rank %d = %s, want %s", i+1, scores[i].Address.Hex(), want.Hex())
+		}
+	}
+
+	// 0.24 ETH profit minus 0.005 ETH fee over 500k gas
+	if got, want := scores[1].NetProfitPerGas, (0.24-0.005)*1e9/DISTRIBUTE_CALL_MAX_GAS; math.Abs(got-want) > 1e-9 {
+		t.Errorf("NetProfitPerGas = %v, want %v", got, want)
+	}
+	// 0.08 ETH profit minus 0.001 ETH fee over 100k gas
+	if got, want := scores[0].NetProfitPerGas, (0.08-0.001)*1e9/100000; math.Abs(got-want) > 1e-9 {
+		t.Errorf("NetProfitPerGas = %v, want %v", got, want)
+	}
+	if scores[2].NetProfitPerGas >= 0 {
+		t.Errorf("minipool without rETH share should have a negative score, got %v", scores[2].NetProfitPerGas)
+	}
+}
+
+func Test_selectMinipoolsForGasBudget(t *testing.T) {
+	large := common.HexToAddress("0x1")
+	medium := common.HexToAddress("0x2")
+	small := common.HexToAddress("0x3")
+	shares := []MinipoolShare{
+		{Address: small, RethShare: big.NewInt(1e18)},
+		{Address: large, RethShare: big.NewInt(3e18)},
+		{Address: medium, RethShare: big.NewInt(2e18)},
+	}
+	gasLimits := map[common.Address]uint64{
+		large:  300000,
+		medium: 300000,
+		small:  100000,
+	}
+
+	// the medium minipool no longer fits after the large one, the small one still does
+	included, deferred := selectMinipoolsForGasBudget(shares, 450000, gasLimits)
+
+	if len(included) != 2 || included[0] != small || included[1] != large {
+		t.Errorf("included = %v, want [%s %s]", included, small.Hex(), large.Hex())
+	}
+	if len(deferred) != 1 || deferred[0] != medium {
+		t.Errorf("deferred = %v, want [%s]", deferred, medium.Hex())
+	}
+}
+
+func Test_verifySubmission(t *testing.T) {
+	distributeTx := newTestTx(0, DISTRIBUTE_CALL_MAX_GAS)
+	arbitrageTx := newTestTx(1, ARBITRAGE_UNISWAP_CALL_MAX_GAS)
+
+	newBundle := func() *flashbots_client.Bundle {
+		bundle := flashbots_client.NewBundleWithTransactions([]*types.Transaction{distributeTx, arbitrageTx})
+		bundle.UseAllBuilders(1)
+		if err := bundle.SetTargetBlockNumber(100); err != nil {
+			t.Fatal(err)
+		}
+		return bundle
+	}
+
+	simulated, err := snapshotBundle(flashbots_client.NewBundleWithTransactions([]*types.Transaction{distributeTx, arbitrageTx}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// builders and target block are set after the simulation and must not change the txs
+	if err := verifySubmission(simulated, newBundle(), 4); err != nil {
+		t.Errorf("verifySubmission() error = %v for an unchanged bundle", err)
+	}
+
+	appended := newBundle()
+	appended.AddTransaction(newTestTx(2, DISTRIBUTE_CALL_MAX_GAS))
+	if err := verifySubmission(simulated, appended, 4); err == nil {
+		t.Error("verifySubmission() accepted a bundle with an added tx")
+	}
+
+	replaced := flashbots_client.NewBundleWithTransactions([]*types.Transaction{distributeTx, newTestTx(1, ARBITRAGE_UNISWAP_CALL_MAX_GAS+1)})
+	if err := replaced.SetTargetBlockNumber(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifySubmission(simulated, replaced, 4); err == nil {
+		t.Error("verifySubmission() accepted a bundle with a modified tx")
+	}
+
+	reordered := flashbots_client.NewBundleWithTransactions([]*types.Transaction{arbitrageTx, distributeTx})
+	if err := reordered.SetTargetBlockNumber(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifySubmission(simulated, reordered, 4); err == nil {
+		t.Error("verifySubmission() accepted a reordered bundle")
+	}
+}
@@ -0,0 +1,91 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+)
+
+// executeFeeLadder resubmits the bundle once per step of the fee ladder, each attempt with the next higher tip,
+// until it is included. Every attempt is rebuilt and simulated with the current state. Steps above
+// MaxPriorityFee are capped, the ladder ends after the first capped step.
+func executeFeeLadder(ctx context.Context, logger *slog.Logger, dataIn *DataIn, report *RunReport) error {
+	for i, step := range dataIn.FeeLadder {
+		tip, capped := capTip(step, dataIn.MaxPriorityFee)
+
+		attempt := *dataIn
+		attempt.tipOverride = tip
+		// the user confirmed the first attempt, the following ones only raise the tip
+		if i > 0 {
+			attempt.SkipConfirmation = true
+		}
+
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Printf("Fee ladder step %d/%d: tip of %.2f gwei.\n\n", i+1, len(dataIn.FeeLadder), weiToGwei(tip))
+		}
+
+		err := executeDistribute(ctx, logger, &attempt, report)
+		logger.Info("fee ladder step",
+			slog.Int("step", i+1),
+			slog.Float64("tipGwei", weiToGwei(tip)),
+			slog.Bool("included", report.Included),
+		)
+		if err != nil {
+			return err
+		}
+
+		if report.Included || report.DistributedElsewhere || report.DryRun || report.NothingToDistribute {
+			return nil
+		}
+
+		if capped {
+			if logger.Enabled(ctx, slog.LevelInfo) && i < len(dataIn.FeeLadder)-1 {
+				fmt.Println("Reached the max priority fee, skipping the remaining fee ladder steps.")
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// applyTipSettings replaces the suggested fees with the fixed ones and the fee ladder step and applies the max priority fee.
+// The fee cap is raised by the ladder tip, the suggested gas price it is based on only covers the suggested tip.
+// A fixed fee cap is never raised, the tip is capped by it instead.
+func applyTipSettings(dataIn DataIn, feeCap, tip *big.Int) (*big.Int, *big.Int) {
+	if dataIn.tipOverride == nil && dataIn.MaxPriorityFee == nil && dataIn.FeeCap == nil && dataIn.TipCap == nil {
+		return feeCap, tip
+	}
+
+	if dataIn.TipCap != nil {
+		tip = dataIn.TipCap
+	}
+	if dataIn.tipOverride != nil {
+		tip = dataIn.tipOverride
+		feeCap = new(big.Int).Add(feeCap, tip)
+	}
+	tip, _ = capTip(tip, dataIn.MaxPriorityFee)
+
+	if dataIn.FeeCap != nil {
+		feeCap = dataIn.FeeCap
+		if tip.Cmp(feeCap) > 0 {
+			tip = feeCap
+		}
+	}
+
+	return feeCap, tip
+}
+
+func capTip(tip, maxPriorityFee *big.Int) (*big.Int, bool) {
+	if maxPriorityFee != nil && tip.Cmp(maxPriorityFee) >= 0 {
+		return maxPriorityFee, true
+	}
+
+	return tip, false
+}
+
+func weiToGwei(amount *big.Int) float64 {
+	gwei, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(big.NewInt(1e9))).Float64()
+	return gwei
+}
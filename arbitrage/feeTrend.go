@@ -0,0 +1,120 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+)
+
+const (
+	FEE_TREND_BLOCKS     = 10
+	FEE_TREND_MIN_CHANGE = 0.01 // change of the base fee per block below which it counts as stable
+	FEE_TREND_WAIT       = 2    // blocks suggested to wait on a falling base fee that is already below break-even
+	FEE_TREND_MAX_WAIT   = MAX_VALID_BLOCKS
+)
+
+type FeeTrend struct {
+	BaseFee          *big.Int // base fee of the next block
+	BreakEvenBaseFee *big.Int // base fee at which the fees of the bundle eat the whole profit, nil if the tips already do
+	ChangePerBlock   float64  // average relative change of the base fee per block
+	WaitBlocks       uint64   // suggested blocks to wait, 0 to submit now
+	Improvement      *big.Int // expected fee saving of waiting WaitBlocks at the current trend
+}
+
+// estimateFeeTrend compares submitting now against waiting, based on the base fee trend of the last
+// FEE_TREND_BLOCKS blocks from a single eth_feeHistory call. The trend is extrapolated, so this is advisory only.
+func estimateFeeTrend(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle, simulatedGas []uint64, expectedProfit *big.Int) (*FeeTrend, error) {
+	history, err := dataIn.Client.FeeHistory(ctx, FEE_TREND_BLOCKS, nil, nil)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get fee history"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	// the fee history includes the base fee of the next block as last entry
+	if len(history.BaseFee) < 2 {
+		return nil, errors.New("fee history is too short")
+	}
+
+	gas := uint64(0)
+	tipCost := new(big.Int)
+	for index, tx := range bundle.Transactions() {
+		txGas := tx.Gas()
+		if index < len(simulatedGas) {
+			txGas = simulatedGas[index]
+		}
+		gas += txGas
+		tipCost.Add(tipCost, new(big.Int).Mul(tx.GasTipCap(), new(big.Int).SetUint64(txGas)))
+	}
+	if gas == 0 {
+		return nil, errors.New("bundle uses no gas")
+	}
+
+	return feeTrendFromHistory(history.BaseFee, gas, tipCost, expectedProfit), nil
+}
+
+func feeTrendFromHistory(baseFees []*big.Int, gas uint64, tipCost, expectedProfit *big.Int) *FeeTrend {
+	first, _ := new(big.Float).SetInt(baseFees[0]).Float64()
+	next, _ := new(big.Float).SetInt(baseFees[len(baseFees)-1]).Float64()
+
+	trend := &FeeTrend{
+		BaseFee:     baseFees[len(baseFees)-1],
+		Improvement: big.NewInt(0),
+	}
+	if first > 0 {
+		// geometric mean, the base fee changes by a factor per block
+		trend.ChangePerBlock = math.Pow(next/first, 1/float64(len(baseFees)-1)) - 1
+	}
+
+	if margin := new(big.Int).Sub(expectedProfit, tipCost); margin.Sign() > 0 {
+		trend.BreakEvenBaseFee = new(big.Int).Div(margin, new(big.Int).SetUint64(gas))
+	}
+
+	if trend.ChangePerBlock > -FEE_TREND_MIN_CHANGE {
+		return trend
+	}
+
+	// with a falling base fee above break-even, wait until it is expected to drop below
+	trend.WaitBlocks = FEE_TREND_WAIT
+	if trend.BreakEvenBaseFee != nil && trend.BaseFee.Cmp(trend.BreakEvenBaseFee) > 0 && trend.BreakEvenBaseFee.Sign() > 0 {
+		breakEven, _ := new(big.Float).SetInt(trend.BreakEvenBaseFee).Float64()
+		blocks := math.Ceil(math.Log(breakEven/next) / math.Log(1+trend.ChangePerBlock))
+		trend.WaitBlocks = uint64(min(max(blocks, 1), FEE_TREND_MAX_WAIT))
+	}
+
+	expectedBaseFee := next * math.Pow(1+trend.ChangePerBlock, float64(trend.WaitBlocks))
+	trend.Improvement, _ = new(big.Float).Mul(big.NewFloat(next-expectedBaseFee), new(big.Float).SetUint64(gas)).Int(nil)
+
+	return trend
+}
+
+func printFeeTrend(trend *FeeTrend) {
+	if trend == nil {
+		return
+	}
+
+	breakEven := "none, the tips exceed the profit"
+	if trend.BreakEvenBaseFee != nil {
+		breakEven = fmt.Sprintf("%.2f Gwei", weiToGwei(trend.BreakEvenBaseFee))
+	}
+	fmt.Printf("    Base fee: %.2f Gwei, break-even: %s. ", weiToGwei(trend.BaseFee), breakEven)
+
+	switch {
+	case trend.WaitBlocks > 0:
+		fmt.Printf("Base fee is trending down (%.1f%% per block), waiting ~%d blocks may improve net by ~%.6f ETH.\n",
+			trend.ChangePerBlock*100,
+			trend.WaitBlocks,
+			weiToEth(trend.Improvement),
+		)
+	case trend.ChangePerBlock >= FEE_TREND_MIN_CHANGE:
+		fmt.Printf("Submit now, fees rising (+%.1f%% per block).\n", trend.ChangePerBlock*100)
+	default:
+		fmt.Print("Fees are stable, submit now.\n")
+	}
+}
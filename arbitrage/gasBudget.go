@@ -0,0 +1,128 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// applyGasBudget limits the minipools to the ones fitting into dataIn.GasBudget.
+// The final tx (arbitrage or burn) is always part of the bundle, its max gas is reserved first, together
+// with the donation tx if a donation is set.
+// The remaining budget is filled with the minipools sending the most ETH to rETH, as the
+// arbitrage profit grows with that amount. Each minipool takes the gas limit of its distribute tx.
+func applyGasBudget(ctx context.Context, logger *slog.Logger, dataIn DataIn, gasLimits map[common.Address]uint64) (included, deferred []common.Address, err error) {
+	if dataIn.GasBudget == 0 {
+		return dataIn.MinipoolAddresses, nil, nil
+	}
+
+	var finalTxGas uint64
+	switch {
+	case dataIn.LocalReth:
+		finalTxGas = BURN_CALL_MAX_GAS
+	case dataIn.Protocol == UniswapProtocol:
+		finalTxGas = ARBITRAGE_UNISWAP_CALL_MAX_GAS
+	default:
+		// paraswap is the more expensive option, assume it to stay within the budget either way
+		finalTxGas = ARBITRAGE_PARASWAP_CALL_MAX_GAS
+	}
+	if dataIn.DonatePct > 0 {
+		finalTxGas += DONATION_GAS
+	}
+
+	cheapest := uint64(0)
+	for _, minipool := range dataIn.MinipoolAddresses {
+		if cheapest == 0 || gasLimits[minipool] < cheapest {
+			cheapest = gasLimits[minipool]
+		}
+	}
+	if dataIn.GasBudget < finalTxGas+cheapest {
+		return nil, nil, fmt.Errorf("gas budget of %d is too low for a single minipool, at least %d is required", dataIn.GasBudget, finalTxGas+cheapest)
+	}
+
+	shares, err := CalculateMinipoolShares(ctx, logger, dataIn.Client, dataIn.MinipoolAddresses, dataIn.Ratelimit)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("failed to calculate minipool shares"), err)
+	}
+
+	included, deferred = selectMinipoolsForGasBudget(shares, dataIn.GasBudget-finalTxGas, gasLimits)
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Printf("Gas budget of %d allows %d of %d minipools:\n", dataIn.GasBudget, len(included), len(dataIn.MinipoolAddresses))
+		for _, minipool := range included {
+			fmt.Printf("    Included: %s\n", minipool.Hex())
+		}
+		for _, minipool := range deferred {
+			fmt.Printf("    Deferred: %s\n", minipool.Hex())
+		}
+		fmt.Println()
+	}
+
+	return included, deferred, nil
+}
+
+// selectMinipoolsForGasBudget greedily picks the minipools with the largest rETH share, skipping the ones
+// whose gas limit no longer fits into the budget. Both lists keep the order of the input.
+func selectMinipoolsForGasBudget(shares []MinipoolShare, budget uint64, gasLimits map[common.Address]uint64) (included, deferred []common.Address) {
+	order := make([]int, len(shares))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return shares[order[a]].RethShare.Cmp(shares[order[b]].RethShare) > 0
+	})
+
+	selected := make([]bool, len(shares))
+	used := uint64(0)
+	for _, i := range order {
+		// nothing to gain from minipools without a rETH share
+		if shares[i].RethShare.Cmp(big.NewInt(0)) <= 0 {
+			break
+		}
+		gas := gasLimits[shares[i].Address]
+		if used+gas > budget {
+			continue
+		}
+		selected[i] = true
+		used += gas
+	}
+
+	for i, share := range shares {
+		if selected[i] {
+			included = append(included, share.Address)
+		} else {
+			deferred = append(deferred, share.Address)
+		}
+	}
+
+	return included, deferred
+}
+
+// checkBlockGasLimit errors if the bundle can never be included because its txs exceed the gas limit of a block
+func checkBlockGasLimit(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle) error {
+	header, err := dataIn.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return errors.Join(errors.New("failed to get latest block"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	bundleGas := uint64(0)
+	for _, tx := range bundle.Transactions() {
+		bundleGas += tx.Gas()
+	}
+
+	if bundleGas > header.GasLimit {
+		return fmt.Errorf("bundle gas of %d exceeds the block gas limit of %d, distribute fewer minipools at once (e.g. with --bundle-size or --gas-budget)", bundleGas, header.GasLimit)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package arbitrage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// GasLogRecord is one line of the gas log, written for every submitted bundle
+type GasLogRecord struct {
+	Time       time.Time   `json:"time"`
+	NetworkId  uint64      `json:"networkId"`
+	Label      string      `json:"label,omitempty"`
+	BundleHash common.Hash `json:"bundleHash"`
+	Included   bool        `json:"included"`
+	Txs        []GasLogTx  `json:"txs"`
+}
+
+// GasLogTx compares the simulated gas of a bundle tx with the gas it used on chain
+type GasLogTx struct {
+	Hash         common.Hash `json:"hash"`
+	Type         string      `json:"type"` // distribute, arbitrage, burn or donation
+	GasLimit     uint64      `json:"gasLimit"`
+	EstimatedGas uint64      `json:"estimatedGas"`        // gas used in the simulation
+	ActualGas    uint64      `json:"actualGas,omitempty"` // gas used on chain, only known if included
+}
+
+// newGasLogRecord collects the gas of the bundle txs. If the bundle was included, the actual gas
+// is taken from the receipts. A missing receipt leaves the actual gas of that tx unset.
+func newGasLogRecord(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle, arbitrageTx, donationTx *types.Transaction, simulatedGas []uint64, bundleHash common.Hash, included bool) GasLogRecord {
+	finalTxType := "arbitrage"
+	if dataIn.LocalReth {
+		finalTxType = "burn"
+	}
+
+	record := GasLogRecord{
+		Time:       time.Now().UTC(),
+		NetworkId:  dataIn.NetworkId,
+		Label:      dataIn.Label,
+		BundleHash: bundleHash,
+		Included:   included,
+		Txs:        []GasLogTx{},
+	}
+
+	for i, tx := range bundle.Transactions() {
+		logTx := GasLogTx{
+			Hash:     tx.Hash(),
+			Type:     "distribute",
+			GasLimit: tx.Gas(),
+		}
+		if tx.Hash() == arbitrageTx.Hash() {
+			logTx.Type = finalTxType
+		}
+		if donationTx != nil && tx.Hash() == donationTx.Hash() {
+			logTx.Type = "donation"
+		}
+		if i < len(simulatedGas) {
+			logTx.EstimatedGas = simulatedGas[i]
+		}
+
+		if included {
+			receipt, err := dataIn.Client.TransactionReceipt(ctx, tx.Hash())
+			if err == nil {
+				logTx.ActualGas = receipt.GasUsed
+			}
+			if dataIn.Ratelimit > 0 {
+				time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+			}
+		}
+
+		record.Txs = append(record.Txs, logTx)
+	}
+
+	return record
+}
+
+// appendGasLog appends the record as a single JSON line
+func appendGasLog(path string, record GasLogRecord) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.Join(errors.New("failed to create log file directory"), err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Join(errors.New("failed to encode gas log record"), err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to open log file"), err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		return errors.Join(errors.New("failed to write log file"), err)
+	}
+
+	return nil
+}
+
+// ReadGasLog reads all records of a gas log file
+func ReadGasLog(path string) ([]GasLogRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to open log file"), err)
+	}
+	defer file.Close()
+
+	records := []GasLogRecord{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record GasLogRecord
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to parse line %d", line), err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Join(errors.New("failed to read log file"), err)
+	}
+
+	return records, nil
+}
+
+// GasStats summarizes the estimation error of one tx type, in percent of the estimated gas.
+// A positive error means the tx used more gas on chain than in the simulation.
+type GasStats struct {
+	Type              string
+	Count             int
+	MeanErrorPct      float64
+	MedianErrorPct    float64
+	MaxLimitUsagePct  float64 // highest actual gas in percent of the gas limit
+	MeanLimitUsagePct float64
+}
+
+// GetGasStats calculates the stats per tx type over all txs with known estimated and actual gas
+func GetGasStats(records []GasLogRecord) []GasStats {
+	errorsByType := map[string][]float64{}
+	usageByType := map[string][]float64{}
+	for _, record := range records {
+		for _, tx := range record.Txs {
+			if tx.EstimatedGas == 0 || tx.ActualGas == 0 {
+				continue
+			}
+
+			errorsByType[tx.Type] = append(errorsByType[tx.Type], (float64(tx.ActualGas)-float64(tx.EstimatedGas))/float64(tx.EstimatedGas)*100)
+			if tx.GasLimit > 0 {
+				usageByType[tx.Type] = append(usageByType[tx.Type], float64(tx.ActualGas)/float64(tx.GasLimit)*100)
+			}
+		}
+	}
+
+	stats := []GasStats{}
+	for txType, errorPcts := range errorsByType {
+		stat := GasStats{
+			Type:           txType,
+			Count:          len(errorPcts),
+			MeanErrorPct:   mean(errorPcts),
+			MedianErrorPct: median(errorPcts),
+		}
+
+		usage := usageByType[txType]
+		stat.MeanLimitUsagePct = mean(usage)
+		for _, pct := range usage {
+			stat.MaxLimitUsagePct = max(stat.MaxLimitUsagePct, pct)
+		}
+
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(a, b int) bool {
+		return stats[a].Type < stats[b].Type
+	})
+
+	return stats
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,54 @@
+package arbitrage
+
+import (
+	"testing"
+)
+
+func Test_GetGasStats(t *testing.T) {
+	records := []GasLogRecord{
+		{
+			Included: true,
+			Txs: []GasLogTx{
+				{Type: "distribute", GasLimit: 500000, EstimatedGas: 400000, ActualGas: 440000},
+				{Type: "arbitrage", GasLimit: 350000, EstimatedGas: 200000, ActualGas: 190000},
+			},
+		},
+		{
+			Included: true,
+			Txs: []GasLogTx{
+				{Type: "distribute", GasLimit: 500000, EstimatedGas: 400000, ActualGas: 400000},
+				{Type: "distribute", GasLimit: 500000, EstimatedGas: 400000, ActualGas: 500000},
+			},
+		},
+		{
+			// not included, no actual gas
+			Txs: []GasLogTx{
+				{Type: "distribute", GasLimit: 500000, EstimatedGas: 400000},
+			},
+		},
+	}
+
+	stats := GetGasStats(records)
+	if len(stats) != 2 {
+		t.Fatalf("GetGasStats() returned %d types, want 2", len(stats))
+	}
+
+	arbitrageStats, distributeStats := stats[0], stats[1]
+	if arbitrageStats.Type != "arbitrage" || arbitrageStats.Count != 1 || arbitrageStats.MeanErrorPct != -5 {
+		t.Errorf("arbitrage stats = %+v", arbitrageStats)
+	}
+
+	// errors of 10%, 0% and 25%
+	if distributeStats.Type != "distribute" || distributeStats.Count != 3 {
+		t.Fatalf("distribute stats = %+v", distributeStats)
+	}
+	if distributeStats.MeanErrorPct != 35.0/3 {
+		t.Errorf("MeanErrorPct = %v, want %v", distributeStats.MeanErrorPct, 35.0/3)
+	}
+	if distributeStats.MedianErrorPct != 10 {
+		t.Errorf("MedianErrorPct = %v, want 10", distributeStats.MedianErrorPct)
+	}
+	if distributeStats.MaxLimitUsagePct != 100 {
+		t.Errorf("MaxLimitUsagePct = %v, want 100", distributeStats.MaxLimitUsagePct)
+	}
+}
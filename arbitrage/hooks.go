@@ -0,0 +1,95 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// a hook that takes longer is killed, it must not block the tool
+const HOOK_TIMEOUT = 30 * time.Second
+
+// runHooks executes the configured success or failure command with the run details in its environment.
+// Hooks are best-effort, a failing hook is logged but does not change the result of the run.
+// The hook does not share the deadline of the run, a failure hook has to run even after --max-runtime.
+func runHooks(ctx context.Context, logger *slog.Logger, dataIn *DataIn, report *RunReport) {
+	hook := dataIn.OnFailureCmd
+	status := "failure"
+	if report.Succeeded() {
+		hook = dataIn.OnSuccessCmd
+		status = "success"
+	}
+
+	if hook == "" {
+		return
+	}
+
+	hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), HOOK_TIMEOUT)
+	defer cancel()
+
+	cmd := exec.CommandContext(hookCtx, "sh", "-c", hook)
+	// background processes of the hook may keep the output open after it was killed
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(), hookEnv(status, report)...)
+	if dataIn.Label != "" {
+		cmd.Env = append(cmd.Env, "RP_ARB_LABEL="+dataIn.Label)
+	}
+
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if err != nil {
+		logger.Warn("hook failed",
+			slog.String("status", status),
+			slog.String("cmd", hook),
+			slog.Int("exitCode", exitCode),
+			slog.String("error", err.Error()),
+			slog.String("output", string(output)),
+		)
+		return
+	}
+
+	logger.Info("hook executed",
+		slog.String("status", status),
+		slog.String("cmd", hook),
+		slog.Int("exitCode", exitCode),
+		slog.String("output", string(output)),
+	)
+}
+
+func hookEnv(status string, report *RunReport) []string {
+	env := []string{
+		"RP_ARB_STATUS=" + status,
+		"RP_ARB_MINIPOOL_COUNT=" + strconv.Itoa(report.MinipoolCount),
+		"RP_ARB_DRY_RUN=" + strconv.FormatBool(report.DryRun),
+		"RP_ARB_INCLUDED=" + strconv.FormatBool(report.Included),
+		"RP_ARB_DISTRIBUTED_ELSEWHERE=" + strconv.FormatBool(report.DistributedElsewhere),
+	}
+
+	if report.TxHash != (common.Hash{}) {
+		env = append(env, "RP_ARB_TX_HASH="+report.TxHash.Hex())
+	}
+	if report.BundleHash != (common.Hash{}) {
+		env = append(env, "RP_ARB_BUNDLE_HASH="+report.BundleHash.Hex())
+	}
+	if report.ExpectedProfit != nil {
+		env = append(env, "RP_ARB_EXPECTED_PROFIT_WEI="+report.ExpectedProfit.String())
+	}
+	if report.RethShare != nil {
+		env = append(env, "RP_ARB_RETH_SHARE_WEI="+report.RethShare.String())
+	}
+	if report.Err != nil {
+		env = append(env, fmt.Sprintf("RP_ARB_ERROR=%s", report.Err.Error()))
+	}
+
+	return env
+}
@@ -0,0 +1,29 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_runHooksAfterCancel(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "hook")
+	dataIn := &DataIn{OnFailureCmd: "echo \"$RP_ARB_STATUS\" > " + marker}
+	report := &RunReport{Err: errors.New("max runtime exceeded")}
+
+	// the run context is done once --max-runtime passed, the failure hook must still run
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runHooks(ctx, slog.Default(), dataIn, report)
+
+	output, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("failure hook did not run: %v", err)
+	}
+	if string(output) != "failure\n" {
+		t.Errorf("hook output = %q, want %q", output, "failure\n")
+	}
+}
@@ -0,0 +1,135 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ErrDuplicateSubmission = errors.New("duplicate submission suppressed")
+
+const (
+	submissionPending     = "pending"
+	submissionIncluded    = "included"
+	submissionNotIncluded = "not-included"
+	submissionFailed      = "failed"
+)
+
+type submission struct {
+	Key         common.Hash `json:"key"` // hash of the sorted minipool set
+	TargetBlock uint64      `json:"targetBlock"`
+	SubmittedAt time.Time   `json:"submittedAt"`
+	Status      string      `json:"status"`
+}
+
+// submissionKey identifies a minipool set independent of the order it was given in
+func submissionKey(minipools []common.Address) common.Hash {
+	sorted := make([]string, len(minipools))
+	for i, minipool := range minipools {
+		sorted[i] = strings.ToLower(minipool.Hex())
+	}
+	sort.Strings(sorted)
+
+	return crypto.Keccak256Hash([]byte(strings.Join(sorted, ",")))
+}
+
+// DefaultSubmissionLogPath returns the submission log location in the user cache directory
+func DefaultSubmissionLogPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "rocketpoolArbitrage", "submissions.json")
+}
+
+func loadSubmissions(path string) ([]submission, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []submission{}, nil
+	}
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read submission log"), err)
+	}
+
+	var submissions []submission
+	err = json.Unmarshal(data, &submissions)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to parse submission log"), err)
+	}
+
+	return submissions, nil
+}
+
+func writeSubmissions(path string, submissions []submission) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.Join(errors.New("failed to create submission log directory"), err)
+	}
+
+	data, err := json.MarshalIndent(submissions, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode submission log"), err)
+	}
+
+	err = os.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to write submission log"), err)
+	}
+
+	return nil
+}
+
+// checkDuplicateSubmission refuses a bundle for a minipool set that was already submitted within the window.
+// The same set for the same target block is always a duplicate. Within the window, a submission that is
+// still pending or was included is a duplicate too, while a retry after a failed or not included one is allowed.
+func checkDuplicateSubmission(submissions []submission, key common.Hash, targetBlock uint64, window time.Duration, now time.Time) error {
+	for _, previous := range submissions {
+		if previous.Key != key {
+			continue
+		}
+
+		if previous.TargetBlock == targetBlock {
+			return fmt.Errorf("%w: same minipools already submitted for block %d", ErrDuplicateSubmission, targetBlock)
+		}
+
+		if now.Sub(previous.SubmittedAt) > window {
+			continue
+		}
+
+		if previous.Status == submissionPending || previous.Status == submissionIncluded {
+			return fmt.Errorf("%w: same minipools submitted %s ago (%s)", ErrDuplicateSubmission, now.Sub(previous.SubmittedAt).Round(time.Second), previous.Status)
+		}
+	}
+
+	return nil
+}
+
+// recordSubmission stores the status of a submission, entries older than the window are dropped
+func recordSubmission(path string, entry submission, window time.Duration) error {
+	submissions, err := loadSubmissions(path)
+	if err != nil {
+		return err
+	}
+
+	kept := []submission{}
+	for _, previous := range submissions {
+		if previous.Key == entry.Key && previous.TargetBlock == entry.TargetBlock {
+			continue
+		}
+		if time.Since(previous.SubmittedAt) > window {
+			continue
+		}
+		kept = append(kept, previous)
+	}
+	kept = append(kept, entry)
+
+	return writeSubmissions(path, kept)
+}
@@ -0,0 +1,96 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	inclusionLanded    = "landed"
+	inclusionNotLanded = "not-landed"
+	inclusionUnknown   = "unknown"
+)
+
+// confirmInclusion decides the inclusion from the chain instead of the boolean of the relay wait. It polls until
+// the tx is InclusionConfirmations blocks deep (landed), the head passed the last target block without the tx
+// (not landed) or ConfirmationTimeout is reached (unknown). While the tx is missing, the relay is asked how many
+// builders sealed a block with the bundle, the count is returned to tell a lost bundle from a slow rpc.
+func confirmInclusion(ctx context.Context, logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, validBlocks uint64, txHash common.Hash) (string, uint64, int) {
+	ctx, cancel := context.WithTimeout(ctx, dataIn.ConfirmationTimeout)
+	defer cancel()
+
+	// the copies sent by the relay wait, one per target block
+	targets := []*flashbots_client.Bundle{bundle}
+	if validBlocks > 1 {
+		next, err := bundle.GetBundelsForNextNBlocks(validBlocks - 1)
+		if err != nil {
+			logger.Warn("failed to get the bundles of the following blocks, only the first target is checked on the relay", slog.String("error", err.Error()))
+		} else {
+			targets = append(targets, next...)
+		}
+	}
+	lastTarget := bundle.TargetBlockNumber() + validBlocks - 1
+
+	sealedBy := 0
+	for {
+		// the head is read first, a missing receipt then also covers every block up to it
+		head, err := dataIn.Client.BlockNumber(ctx)
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("failed to get block number for the inclusion check", slog.String("error", err.Error()))
+			}
+		} else {
+			receipt, err := dataIn.Client.TransactionReceipt(ctx, txHash)
+			if dataIn.Ratelimit > 0 {
+				time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+			}
+			switch {
+			case err == nil:
+				block := receipt.BlockNumber.Uint64()
+				if head >= block && head-block+1 >= dataIn.InclusionConfirmations {
+					return inclusionLanded, block, sealedBy
+				}
+				logger.Debug("tx found, waiting for confirmations", slog.Uint64("block", block), slog.Uint64("head", head))
+			case errors.Is(err, ethereum.NotFound):
+				sealedBy = relaySealCount(logger, dataIn, targets)
+				logger.Debug("tx not found yet", slog.Uint64("head", head), slog.Uint64("lastTarget", lastTarget), slog.Int("sealedByBuilders", sealedBy))
+				if head > lastTarget {
+					return inclusionNotLanded, 0, sealedBy
+				}
+			case ctx.Err() == nil:
+				logger.Warn("failed to get tx receipt for the inclusion check", slog.String("error", err.Error()))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return inclusionUnknown, 0, sealedBy
+		case <-time.After(CONFIRMATION_POLL_INTERVAL):
+		}
+	}
+}
+
+// relaySealCount sums the builders that sealed a block with the bundle over all target blocks.
+// The stats are informational, a failed request counts as no builder.
+func relaySealCount(logger *slog.Logger, dataIn *DataIn, targets []*flashbots_client.Bundle) int {
+	sealedBy := 0
+	for _, target := range targets {
+		stats, err := dataIn.FbClient.GetBundleStats(target)
+		if err != nil {
+			logger.Debug("failed to get bundle stats", slog.Uint64("targetBlock", target.TargetBlockNumber()), slog.String("error", err.Error()))
+			continue
+		}
+		sealedBy += len(stats.SealedByBuilders)
+	}
+
+	return sealedBy
+}
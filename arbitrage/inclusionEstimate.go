@@ -0,0 +1,74 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+const (
+	INCLUSION_ESTIMATE_BLOCKS     = 20
+	INCLUSION_ESTIMATE_PERCENTILE = 25 // a tip at the lower quartile of a block is usually enough to be picked
+)
+
+const (
+	DEFAULT_VALID_BLOCKS     = 4
+	MAX_VALID_BLOCKS         = 25
+	SLOT_DURATION            = 12 * time.Second
+	INCLUSION_TIMEOUT_MARGIN = 22 * time.Second // the relay reports the inclusion with some delay
+)
+
+// estimateInclusionBlocks gives a rough number of blocks until a tx with the given fees is included,
+// based on a single eth_feeHistory call. A block counts as "would have included" if the max fee covers
+// its base fee and the tip reaches the INCLUSION_ESTIMATE_PERCENTILE reward of that block.
+// Returns 0 if none of the recent blocks would have included the tx.
+func estimateInclusionBlocks(ctx context.Context, dataIn *DataIn, gasFeeCap, gasTipCap *big.Int) (uint64, error) {
+	history, err := dataIn.Client.FeeHistory(ctx, INCLUSION_ESTIMATE_BLOCKS, nil, []float64{INCLUSION_ESTIMATE_PERCENTILE})
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to get fee history"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	return inclusionBlocksFromHistory(history, gasFeeCap, gasTipCap), nil
+}
+
+func inclusionBlocksFromHistory(history *ethereum.FeeHistory, gasFeeCap, gasTipCap *big.Int) uint64 {
+	blocks := len(history.Reward)
+	if blocks == 0 {
+		return 0
+	}
+
+	matching := 0
+	for i := 0; i < blocks; i++ {
+		if i >= len(history.BaseFee) || len(history.Reward[i]) == 0 {
+			continue
+		}
+
+		baseFee := history.BaseFee[i]
+		if gasFeeCap.Cmp(baseFee) < 0 {
+			continue
+		}
+
+		// the effective tip is capped by what is left of the max fee after the base fee
+		effectiveTip := new(big.Int).Sub(gasFeeCap, baseFee)
+		if effectiveTip.Cmp(gasTipCap) > 0 {
+			effectiveTip = gasTipCap
+		}
+
+		if effectiveTip.Cmp(history.Reward[i][0]) >= 0 {
+			matching++
+		}
+	}
+
+	if matching == 0 {
+		return 0
+	}
+
+	// expected value of a geometric distribution, rounded up
+	return uint64((blocks + matching - 1) / matching)
+}
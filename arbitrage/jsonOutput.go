@@ -0,0 +1,197 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type jsonReport struct {
+	NetworkId uint64          `json:"networkId"`
+	Label     string          `json:"label,omitempty"`
+	Success   bool            `json:"success"`
+	Runs      []jsonRunReport `json:"runs"`
+}
+
+type jsonRunReport struct {
+	Success        bool                    `json:"success"`
+	DryRun         bool                    `json:"dryRun"`
+	Included       bool                    `json:"included"`
+	Elsewhere      bool                    `json:"distributedElsewhere"`
+	NothingToDo    bool                    `json:"nothingToDistribute"`
+	Dropped        []common.Address        `json:"droppedMinipools,omitempty"`
+	Minipools      []common.Address        `json:"minipools"`
+	ExpectedProfit string                  `json:"expectedProfitWei,omitempty"`
+	RethShare      string                  `json:"rethShareWei,omitempty"`
+	RethToBurn     string                  `json:"rethToBurnWei,omitempty"`
+	ExchangeRate   *jsonExchangeRateSource `json:"exchangeRate,omitempty"`
+	PoolPrice      *jsonPoolPrice          `json:"poolPrice,omitempty"`
+	BundleHash     string                  `json:"bundleHash,omitempty"`
+	TxHash         string                  `json:"txHash,omitempty"`
+	Txs            []jsonIncludedTx        `json:"txs,omitempty"`
+	Simulation     []jsonSimulatedTx       `json:"simulation,omitempty"`
+	Timings        []jsonPhaseTiming       `json:"timings,omitempty"`
+	Builder        *jsonBuilderInfo        `json:"builder,omitempty"`
+	ConfirmedBlock uint64                  `json:"confirmedBlock,omitempty"`
+	Inclusion      string                  `json:"inclusion,omitempty"`
+	InclusionBlock uint64                  `json:"inclusionBlock,omitempty"`
+	Error          string                  `json:"error,omitempty"`
+}
+
+type jsonIncludedTx struct {
+	Type string `json:"type"`
+	Hash string `json:"hash"`
+	Url  string `json:"url"`
+}
+
+type jsonSimulatedTx struct {
+	Hash              common.Hash    `json:"hash"`
+	To                common.Address `json:"to"`
+	GasUsed           uint64         `json:"gasUsed"`
+	CoinbaseDiff      string         `json:"coinbaseDiffWei,omitempty"`
+	EthSentToCoinbase string         `json:"ethSentToCoinbaseWei,omitempty"`
+	GasFees           string         `json:"gasFeesWei,omitempty"`
+	Reverted          bool           `json:"reverted"`
+}
+
+type jsonPhaseTiming struct {
+	Phase      string `json:"phase"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type jsonBuilderInfo struct {
+	Block        uint64         `json:"block"`
+	Name         string         `json:"name"`
+	FeeRecipient common.Address `json:"feeRecipient"`
+}
+
+type jsonPoolPrice struct {
+	Before         float64 `json:"before"`
+	After          float64 `json:"after"`
+	ImpactPct      float64 `json:"impactPct"`
+	DiscountBefore float64 `json:"discountBeforePct"`
+	DiscountAfter  float64 `json:"discountAfterPct"`
+}
+
+type jsonExchangeRateSource struct {
+	Contract common.Address `json:"contract"`
+	Method   string         `json:"method"`
+	Block    uint64         `json:"block"`
+	Rate     string         `json:"rateWei"`
+}
+
+// writeJSONReport writes the reports of all bundles of this invocation to path.
+// Amounts are decimal strings in wei to avoid any precision loss.
+func writeJSONReport(path string, dataIn *DataIn, reports []*RunReport) error {
+	out := jsonReport{
+		NetworkId: dataIn.NetworkId,
+		Label:     dataIn.Label,
+		Success:   len(reports) > 0,
+		Runs:      []jsonRunReport{},
+	}
+
+	for _, report := range reports {
+		if report == nil {
+			continue
+		}
+
+		run := jsonRunReport{
+			Success:        report.Succeeded(),
+			DryRun:         report.DryRun,
+			Included:       report.Included,
+			Elsewhere:      report.DistributedElsewhere,
+			NothingToDo:    report.NothingToDistribute,
+			Dropped:        report.Dropped,
+			ConfirmedBlock: report.ConfirmedBlock,
+			Inclusion:      report.Inclusion,
+			InclusionBlock: report.InclusionBlock,
+			Minipools:      report.Minipools,
+			ExpectedProfit: bigIntString(report.ExpectedProfit),
+			RethShare:      bigIntString(report.RethShare),
+			RethToBurn:     bigIntString(report.RethToBurn),
+		}
+		if report.ExchangeRate != nil {
+			run.ExchangeRate = &jsonExchangeRateSource{
+				Contract: report.ExchangeRate.Contract,
+				Method:   report.ExchangeRate.Method,
+				Block:    report.ExchangeRate.Block,
+				Rate:     bigIntString(report.ExchangeRate.Rate),
+			}
+		}
+		if report.PoolPrice != nil {
+			run.PoolPrice = &jsonPoolPrice{
+				Before:         report.PoolPrice.Before,
+				After:          report.PoolPrice.After,
+				ImpactPct:      report.PoolPrice.ImpactPct,
+				DiscountBefore: report.PoolPrice.DiscountBefore,
+				DiscountAfter:  report.PoolPrice.DiscountAfter,
+			}
+		}
+		if report.BundleHash != (common.Hash{}) {
+			run.BundleHash = report.BundleHash.Hex()
+		}
+		if report.TxHash != (common.Hash{}) {
+			run.TxHash = report.TxHash.Hex()
+		}
+		for _, tx := range report.Txs {
+			run.Txs = append(run.Txs, jsonIncludedTx{
+				Type: strings.ToLower(tx.Type),
+				Hash: tx.Hash.Hex(),
+				Url:  explorerTxUrl(dataIn, tx.Hash),
+			})
+		}
+		for _, tx := range report.Simulation {
+			run.Simulation = append(run.Simulation, jsonSimulatedTx{
+				Hash:              tx.Hash,
+				To:                tx.To,
+				GasUsed:           tx.GasUsed,
+				CoinbaseDiff:      bigIntString(tx.CoinbaseDiff),
+				EthSentToCoinbase: bigIntString(tx.EthSentToCoinbase),
+				GasFees:           bigIntString(tx.GasFees),
+				Reverted:          tx.Reverted,
+			})
+		}
+		for _, timing := range report.Timings {
+			run.Timings = append(run.Timings, jsonPhaseTiming{
+				Phase:      timing.Phase,
+				DurationMs: timing.Duration.Milliseconds(),
+			})
+		}
+		if report.Builder != nil {
+			run.Builder = &jsonBuilderInfo{
+				Block:        report.Builder.Block,
+				Name:         report.Builder.Name,
+				FeeRecipient: report.Builder.FeeRecipient,
+			}
+		}
+		if report.Err != nil {
+			run.Error = report.Err.Error()
+		}
+
+		out.Success = out.Success && run.Success
+		out.Runs = append(out.Runs, run)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode json report"), err)
+	}
+
+	err = os.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to write json report"), err)
+	}
+
+	return nil
+}
+
+func bigIntString(value *big.Int) string {
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
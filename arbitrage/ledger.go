@@ -0,0 +1,223 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"rocketpoolArbitrage/arbitrage/contract"
+	"strconv"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var ledgerHeader = []string{"time", "networkId", "label", "block", "bundleHash", "arbitrageTx", "minipools", "expectedProfitEth", "realizedProfitEth", "feesPaidEth", "netProfitEth", "cumulativeNetProfitEth"}
+
+// LedgerRow is one included bundle in the P&L ledger
+type LedgerRow struct {
+	Time             time.Time
+	NetworkId        uint64
+	Label            string
+	Block            uint64
+	BundleHash       common.Hash
+	ArbitrageTx      common.Hash
+	Minipools        int
+	ExpectedProfit   *big.Int // swap profit of the simulation, before fees
+	RealizedProfit   *big.Int // profit of the Arbitrage event in the inclusion block, zero if the arbitrage tx reverted
+	FeesPaid         *big.Int // gas used times the effective gas price of all bundle txs
+	NetProfit        *big.Int // realized profit minus the fees paid
+	CumulativeProfit *big.Int // net profit of this and all previous rows
+}
+
+// newLedgerRow takes the realized profit and the fees paid from the receipts of the included bundle
+func newLedgerRow(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle, arbTxHash, bundleHash common.Hash, minipools int, expectedProfit *big.Int) (LedgerRow, error) {
+	row := LedgerRow{
+		Time:           time.Now().UTC(),
+		NetworkId:      dataIn.NetworkId,
+		Label:          dataIn.Label,
+		BundleHash:     bundleHash,
+		ArbitrageTx:    arbTxHash,
+		Minipools:      minipools,
+		ExpectedProfit: expectedProfit,
+		RealizedProfit: new(big.Int),
+		FeesPaid:       new(big.Int),
+	}
+
+	arbitrageContractAddress, err := GetArbitrageContractAddress(dataIn.NetworkId)
+	if err != nil {
+		return LedgerRow{}, errors.Join(errors.New("failed to get arbitrage contract address"), err)
+	}
+
+	for _, tx := range bundle.Transactions() {
+		receipt, err := dataIn.Client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return LedgerRow{}, errors.Join(fmt.Errorf("%s: failed to get receipt", tx.Hash().Hex()), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		row.Block = receipt.BlockNumber.Uint64()
+		row.FeesPaid.Add(row.FeesPaid, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice))
+
+		if tx.Hash() == arbTxHash {
+			row.RealizedProfit, err = arbitrageProfit(receipt, arbitrageContractAddress)
+			if err != nil {
+				return LedgerRow{}, errors.Join(fmt.Errorf("%s: failed to read the arbitrage profit", tx.Hash().Hex()), err)
+			}
+		}
+	}
+	row.NetProfit = new(big.Int).Sub(row.RealizedProfit, row.FeesPaid)
+
+	return row, nil
+}
+
+// arbitrageProfit sums the profit of the Arbitrage events the contract emitted in the receipt.
+// A reverted arbitrage tx has no events and no profit.
+func arbitrageProfit(receipt *types.Receipt, arbitrageContractAddress common.Address) (*big.Int, error) {
+	profit := new(big.Int)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return profit, nil
+	}
+
+	filterer, err := contract.NewContractFilterer(arbitrageContractAddress, nil)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to bind arbitrage contract"), err)
+	}
+
+	parsedAbi, err := contract.ContractMetaData.GetAbi()
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to parse arbitrage contract abi"), err)
+	}
+	eventId := parsedAbi.Events["Arbitrage"].ID
+
+	for _, log := range receipt.Logs {
+		if log.Address != arbitrageContractAddress || len(log.Topics) == 0 || log.Topics[0] != eventId {
+			continue
+		}
+
+		event, err := filterer.ParseArbitrage(*log)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to parse Arbitrage event"), err)
+		}
+		profit.Add(profit, event.Profit)
+	}
+
+	return profit, nil
+}
+
+// appendLedger appends the row to the CSV ledger and continues the cumulative profit of its last row.
+// The header is written if the file is new or empty.
+func appendLedger(path string, row *LedgerRow) error {
+	previous, err := readLedgerTotal(path)
+	if err != nil {
+		return err
+	}
+	row.CumulativeProfit = new(big.Int).Add(previous, row.NetProfit)
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.Join(errors.New("failed to create ledger directory"), err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to open ledger"), err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Join(errors.New("failed to stat ledger"), err)
+	}
+
+	writer := csv.NewWriter(file)
+	if info.Size() == 0 {
+		_ = writer.Write(ledgerHeader)
+	}
+	_ = writer.Write([]string{
+		row.Time.Format(time.RFC3339),
+		strconv.FormatUint(row.NetworkId, 10),
+		row.Label,
+		strconv.FormatUint(row.Block, 10),
+		row.BundleHash.Hex(),
+		row.ArbitrageTx.Hex(),
+		strconv.Itoa(row.Minipools),
+		weiToEthString(row.ExpectedProfit),
+		weiToEthString(row.RealizedProfit),
+		weiToEthString(row.FeesPaid),
+		weiToEthString(row.NetProfit),
+		weiToEthString(row.CumulativeProfit),
+	})
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return errors.Join(errors.New("failed to write ledger"), err)
+	}
+
+	return nil
+}
+
+// readLedgerTotal returns the cumulative profit of the last row, zero if the ledger does not exist yet
+func readLedgerTotal(path string) (*big.Int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return new(big.Int), nil
+	}
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to open ledger"), err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	var last []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to read ledger"), err)
+		}
+		last = record
+	}
+
+	if last == nil || last[0] == ledgerHeader[0] {
+		return new(big.Int), nil
+	}
+	if len(last) != len(ledgerHeader) {
+		return nil, fmt.Errorf("ledger row has %d columns, expected %d", len(last), len(ledgerHeader))
+	}
+
+	total, ok := ethStringToWei(last[len(last)-1])
+	if !ok {
+		return nil, fmt.Errorf("invalid cumulative profit in ledger: %s", last[len(last)-1])
+	}
+
+	return total, nil
+}
+
+// weiToEthString formats the amount as ETH without rounding, so the ledger sums stay exact
+func weiToEthString(amount *big.Int) string {
+	return new(big.Rat).SetFrac(amount, big.NewInt(1e18)).FloatString(18)
+}
+
+func ethStringToWei(value string) (*big.Int, bool) {
+	eth, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, false
+	}
+
+	wei := new(big.Rat).Mul(eth, new(big.Rat).SetInt64(1e18))
+	if !wei.IsInt() {
+		return nil, false
+	}
+
+	return wei.Num(), true
+}
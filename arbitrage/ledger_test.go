@@ -0,0 +1,59 @@
+package arbitrage
+
+import (
+	"math/big"
+	"rocketpoolArbitrage/arbitrage/contract"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func Test_arbitrageProfit(t *testing.T) {
+	parsedAbi, err := contract.ContractMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := parsedAbi.Events["Arbitrage"]
+
+	arbitrageContractAddress := common.HexToAddress(Mainnet_arbitrageContractAddressStr)
+	caller := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	receiver := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	data, err := event.Inputs.NonIndexed().Pack(common.Address{}, big.NewInt(9e18), big.NewInt(3e16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	arbitrageLog := &types.Log{
+		Address: arbitrageContractAddress,
+		Topics:  []common.Hash{event.ID, common.BytesToHash(caller.Bytes()), common.BytesToHash(receiver.Bytes())},
+		Data:    data,
+	}
+	// same event signature from another contract
+	foreignLog := &types.Log{
+		Address: receiver,
+		Topics:  arbitrageLog.Topics,
+		Data:    data,
+	}
+
+	tests := []struct {
+		name    string
+		receipt *types.Receipt
+		want    *big.Int
+	}{
+		{"arbitrage event", &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{foreignLog, arbitrageLog}}, big.NewInt(3e16)},
+		{"no event", &types.Receipt{Status: types.ReceiptStatusSuccessful}, big.NewInt(0)},
+		{"reverted", &types.Receipt{Status: types.ReceiptStatusFailed, Logs: []*types.Log{arbitrageLog}}, big.NewInt(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := arbitrageProfit(tt.receipt, arbitrageContractAddress)
+			if err != nil {
+				t.Fatalf("arbitrageProfit() error = %v", err)
+			}
+			if got.Cmp(tt.want) != 0 {
+				t.Errorf("arbitrageProfit() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"rocketpoolArbitrage/beaconchain"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const BEACON_EPOCH_DURATION = 32 * SLOT_DURATION
+
+// filterMinipoolsByAge keeps the minipools whose validator exited at least MinMinipoolAge and at most
+// MaxMinipoolAge ago. The exit epoch is read from the beacon node, a validator that has not exited has no age
+// and is left out. A zero bound is disabled.
+func filterMinipoolsByAge(ctx context.Context, logger *slog.Logger, dataIn *DataIn, minipools []common.Address) ([]common.Address, error) {
+	genesis, err := beaconchain.GetGenesisTime(dataIn.BeaconUrl)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get beacon genesis time"), err)
+	}
+
+	managerAddress, err := getRocketpoolContractAddress(ctx, dataIn.Client, dataIn.NetworkId, "rocketMinipoolManager", dataIn.Ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool manager address"), err)
+	}
+
+	managerABI, err := abi.JSON(strings.NewReader(MinipoolManagerABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool manager ABI"), err)
+	}
+
+	now := time.Now()
+	kept := []common.Address{}
+	for _, minipoolAddress := range minipools {
+		pubkey, err := getMinipoolPubkey(ctx, *dataIn, managerABI, managerAddress, minipoolAddress)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool pubkey", minipoolAddress.Hex()), err)
+		}
+
+		exitEpoch, err := beaconchain.GetValidatorExitEpoch(dataIn.BeaconUrl, pubkey)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get exit epoch", minipoolAddress.Hex()), err)
+		}
+
+		if exitEpoch == math.MaxUint64 {
+			logger.Debug("minipool has not exited, skipped by the age filter", slog.String("minipool", minipoolAddress.Hex()))
+			continue
+		}
+
+		age := now.Sub(genesis.Add(time.Duration(exitEpoch) * BEACON_EPOCH_DURATION))
+		logger.Debug("minipool age", slog.String("minipool", minipoolAddress.Hex()), slog.Uint64("exitEpoch", exitEpoch), slog.Duration("age", age.Round(time.Second)))
+
+		if (dataIn.MinMinipoolAge > 0 && age < dataIn.MinMinipoolAge) || (dataIn.MaxMinipoolAge > 0 && age > dataIn.MaxMinipoolAge) {
+			continue
+		}
+		kept = append(kept, minipoolAddress)
+	}
+
+	return kept, nil
+}
@@ -2,12 +2,15 @@ package arbitrage
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
 	"rocketpoolArbitrage/rocketpoolContracts/rETH"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 func GetMinipoolStatus(ctx context.Context, instance *minipoolDelegate.MinipoolDelegate) (uint8, error) {
@@ -65,10 +68,22 @@ func GetMinipoolNodeAddress(ctx context.Context, instance *minipoolDelegate.Mini
 	return session.GetNodeAddress()
 }
 
+// GetMinipoolNodeFee returns the commission of the minipool, scaled by 1e18
+func GetMinipoolNodeFee(ctx context.Context, instance *minipoolDelegate.MinipoolDelegate) (*big.Int, error) {
+	session := &minipoolDelegate.MinipoolDelegateSession{
+		Contract: instance,
+		CallOpts: bind.CallOpts{
+			Context: ctx,
+		},
+	}
+
+	return session.GetNodeFee()
+}
+
 func ConvertRethToWeth(ctx context.Context, instance *rETH.RETH, rEthAmount *big.Int) (*big.Int, error) {
 	session := &rETH.RETHSession{
 		Contract: instance,
-		TransactOpts: bind.TransactOpts{
+		CallOpts: bind.CallOpts{
 			Context: ctx,
 		},
 	}
@@ -79,10 +94,49 @@ func ConvertRethToWeth(ctx context.Context, instance *rETH.RETH, rEthAmount *big
 func ConvertWethToReth(ctx context.Context, instance *rETH.RETH, wethAmount *big.Int) (*big.Int, error) {
 	session := &rETH.RETHSession{
 		Contract: instance,
-		TransactOpts: bind.TransactOpts{
+		CallOpts: bind.CallOpts{
 			Context: ctx,
 		},
 	}
 
 	return session.GetRethValue(wethAmount)
 }
+
+// GetExchangeRateSource reads the protocol rETH exchange rate pinned to the latest block, so it can be verified independently
+func GetExchangeRateSource(ctx context.Context, client *ethclient.Client, networkId uint64, ratelimit int) (*ExchangeRateSource, error) {
+	rEthContractAddress, err := GetREthContractAddress(networkId)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get rETH contract address"), err)
+	}
+
+	instance, err := rETH.NewRETH(rEthContractAddress, client)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create rETH instance"), err)
+	}
+
+	blockNumber, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get block number"), err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+
+	rate, err := instance.GetExchangeRate(&bind.CallOpts{
+		Context:     ctx,
+		BlockNumber: new(big.Int).SetUint64(blockNumber),
+	})
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get rETH exchange rate"), err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+
+	return &ExchangeRateSource{
+		Contract: rEthContractAddress,
+		Method:   "getRethValue(uint256)",
+		Block:    blockNumber,
+		Rate:     rate,
+	}, nil
+}
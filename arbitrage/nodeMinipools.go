@@ -0,0 +1,181 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"rocketpoolArbitrage/rocketpoolContracts/storage"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// minimal RocketMinipoolManager ABI, only the view functions needed to enumerate the minipools of a node and get their pubkeys
+const MinipoolManagerABI = `[{"inputs":[{"internalType":"address","name":"_nodeAddress","type":"address"}],"name":"getNodeMinipoolCount","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_nodeAddress","type":"address"},{"internalType":"uint256","name":"_index","type":"uint256"}],"name":"getNodeMinipoolAt","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_minipoolAddress","type":"address"}],"name":"getMinipoolPubkey","outputs":[{"internalType":"bytes","name":"","type":"bytes"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_minipoolAddress","type":"address"}],"name":"getMinipoolExists","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"}]`
+
+// GetNodeMinipools enumerates all minipools of the node through the RocketMinipoolManager contract,
+// whose address is looked up in the Rocket Pool storage contract
+func GetNodeMinipools(ctx context.Context, client *ethclient.Client, networkId uint64, nodeAddress common.Address, ratelimit int) ([]common.Address, error) {
+	managerAddress, err := getRocketpoolContractAddress(ctx, client, networkId, "rocketMinipoolManager", ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool manager address"), err)
+	}
+
+	managerABI, err := abi.JSON(strings.NewReader(MinipoolManagerABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool manager ABI"), err)
+	}
+
+	countValues, err := callViewFunction(ctx, client, managerABI, managerAddress, ratelimit, "getNodeMinipoolCount", nodeAddress)
+	if err != nil {
+		return nil, err
+	}
+	count, ok := countValues[0].(*big.Int)
+	if !ok {
+		return nil, errors.New("unexpected getNodeMinipoolCount output")
+	}
+
+	minipools := make([]common.Address, 0, count.Uint64())
+	for i := uint64(0); i < count.Uint64(); i++ {
+		values, err := callViewFunction(ctx, client, managerABI, managerAddress, ratelimit, "getNodeMinipoolAt", nodeAddress, new(big.Int).SetUint64(i))
+		if err != nil {
+			return nil, err
+		}
+		minipool, ok := values[0].(common.Address)
+		if !ok {
+			return nil, errors.New("unexpected getNodeMinipoolAt output")
+		}
+
+		minipools = append(minipools, minipool)
+	}
+
+	return minipools, nil
+}
+
+// getRocketpoolContractAddress looks up a Rocket Pool contract by name in the Rocket Pool storage contract
+func getRocketpoolContractAddress(ctx context.Context, client *ethclient.Client, networkId uint64, name string, ratelimit int) (common.Address, error) {
+	rocketpoolStorageAddress, err := GetRocketpoolStorageAddress(networkId)
+	if err != nil {
+		return common.Address{}, errors.Join(errors.New("failed to get rocketpool storage address"), err)
+	}
+
+	storageInterface, err := storage.NewStorage(rocketpoolStorageAddress, client)
+	if err != nil {
+		return common.Address{}, errors.Join(errors.New("failed to create storage contract instance"), err)
+	}
+
+	key := crypto.Keccak256Hash([]byte("contract.address"), []byte(name))
+	address, err := storageInterface.GetAddress(&bind.CallOpts{Context: ctx}, key)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+
+	return address, nil
+}
+
+func callViewFunction(ctx context.Context, client *ethclient.Client, contractABI abi.ABI, contractAddress common.Address, ratelimit int, method string, args ...interface{}) ([]interface{}, error) {
+	callData, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %v", method, err)
+	}
+
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &contractAddress, Data: callData}, nil)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to call %s", method), err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+
+	values, err := contractABI.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %v", method, err)
+	}
+
+	return values, nil
+}
+
+// GetDistributableNodeMinipools returns the minipools of the node this tool can distribute: V3, staking,
+// not yet finalised and holding more than 8 ETH, i.e. exited. Excluded minipools are skipped.
+func GetDistributableNodeMinipools(ctx context.Context, logger *slog.Logger, dataIn *DataIn) ([]common.Address, error) {
+	minipools, err := GetNodeMinipools(ctx, dataIn.Client, dataIn.NetworkId, *dataIn.NodeAddress, dataIn.Ratelimit)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("node minipools", slog.Int("count", len(minipools)))
+
+	minipools = withoutAddresses(minipools, dataIn.ExcludeMinipools)
+
+	distributable := []common.Address{}
+	for _, minipoolAddress := range minipools {
+		minipoolInstance, err := minipoolDelegate.NewMinipoolDelegate(minipoolAddress, dataIn.Client)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to create minipool instance", minipoolAddress), err)
+		}
+
+		version, err := GetMinipoolDelegateVersion(ctx, minipoolInstance)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool version", minipoolAddress), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		if version != 3 {
+			logger.Debug("skipping minipool, not V3", slog.String("minipool", minipoolAddress.Hex()))
+			continue
+		}
+
+		status, err := GetMinipoolStatus(ctx, minipoolInstance)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool status", minipoolAddress), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		if status != uint8(2) {
+			logger.Debug("skipping minipool, not staking", slog.String("minipool", minipoolAddress.Hex()))
+			continue
+		}
+
+		finalised, err := minipoolInstance.GetFinalised(nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool finalised state", minipoolAddress), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		if finalised {
+			logger.Debug("skipping minipool, finalised", slog.String("minipool", minipoolAddress.Hex()))
+			continue
+		}
+
+		balance, err := dataIn.Client.BalanceAt(ctx, minipoolAddress, nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get minipool balance", minipoolAddress), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+		// below 8 ETH only the rewards could be skimmed, there is nothing to arbitrage
+		if balance.Cmp(big.NewInt(8e18)) <= 0 {
+			logger.Debug("skipping minipool, not exited", slog.String("minipool", minipoolAddress.Hex()))
+			continue
+		}
+
+		distributable = append(distributable, minipoolAddress)
+	}
+
+	return distributable, nil
+}
@@ -0,0 +1,61 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// a wallet that keeps sending txs would otherwise rebuild the bundle forever
+const MAX_NONCE_REFRESHES = 3
+
+// staleNonce reports the first signer whose pending nonce moved past the lowest nonce it uses in the bundle,
+// e.g. because another tx of the node was mined after the bundle was built. Its bundle txs can no longer be included.
+// A fixed --nonce is never considered stale.
+func staleNonce(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle) (*common.Address, error) {
+	if dataIn.Nonce != nil {
+		return nil, nil
+	}
+
+	signers := []common.Address{}
+	lowest := map[common.Address]uint64{}
+	for _, tx := range bundle.Transactions() {
+		sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to recover sender", tx.Hash().Hex()), err)
+		}
+
+		nonce, ok := lowest[sender]
+		if !ok {
+			signers = append(signers, sender)
+		}
+		if !ok || tx.Nonce() < nonce {
+			lowest[sender] = tx.Nonce()
+		}
+	}
+
+	for _, signer := range signers {
+		pending, err := dataIn.Client.PendingNonceAt(ctx, signer)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to get pending nonce of %s", signer.Hex()), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		// the offset only applies to the node's own txs
+		if dataIn.NodeAddress != nil && signer == *dataIn.NodeAddress {
+			pending += dataIn.NonceOffset
+		}
+		if pending > lowest[signer] {
+			return &signer, nil
+		}
+	}
+
+	return nil, nil
+}
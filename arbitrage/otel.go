@@ -0,0 +1,228 @@
+package arbitrage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OTEL_SERVICE_NAME   = "RocketpoolExitArbitrage"
+	OTEL_EXPORT_TIMEOUT = 5 * time.Second
+)
+
+// tracer collects the spans of one run and exports them at the end as OTLP/HTTP json.
+// All span methods are no-ops on a nil span, so tracing can be disabled by simply not starting a tracer.
+type tracer struct {
+	endpoint string
+	traceId  [16]byte
+
+	mu    sync.Mutex
+	spans []*otelSpan
+}
+
+type otelSpan struct {
+	tracer   *tracer
+	spanId   [8]byte
+	parentId *[8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]interface{}
+	err      error
+}
+
+type spanContextKey struct{}
+
+func newTracer(endpoint string) *tracer {
+	t := &tracer{endpoint: strings.TrimSuffix(endpoint, "/")}
+	_, _ = rand.Read(t.traceId[:])
+	return t
+}
+
+// startRoot starts the root span of the run, all spans started from the returned context are its children
+func (t *tracer) startRoot(ctx context.Context, name string) (context.Context, *otelSpan) {
+	span := t.newSpan(name, nil)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func (t *tracer) newSpan(name string, parent *otelSpan) *otelSpan {
+	span := &otelSpan{
+		tracer: t,
+		name:   name,
+		start:  time.Now(),
+		attrs:  map[string]interface{}{},
+	}
+	_, _ = rand.Read(span.spanId[:])
+	if parent != nil {
+		span.parentId = &parent.spanId
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return span
+}
+
+// startSpan starts a child of the span in ctx. Without a span in ctx tracing is disabled and nil is returned.
+func startSpan(ctx context.Context, name string) (context.Context, *otelSpan) {
+	parent := spanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	span := parent.tracer.newSpan(name, parent)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func spanFromContext(ctx context.Context) *otelSpan {
+	span, _ := ctx.Value(spanContextKey{}).(*otelSpan)
+	return span
+}
+
+func (s *otelSpan) setAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.tracer.mu.Lock()
+	s.attrs[key] = value
+	s.tracer.mu.Unlock()
+}
+
+// setEthAttr records a wei amount as ETH, dashboards can aggregate numbers but not decimal strings
+func (s *otelSpan) setEthAttr(key string, amount *big.Int) {
+	if amount == nil {
+		return
+	}
+
+	s.setAttr(key, weiToEth(amount))
+}
+
+func (s *otelSpan) finish(err error) {
+	if s == nil {
+		return
+	}
+
+	s.tracer.mu.Lock()
+	s.end = time.Now()
+	s.err = err
+	s.tracer.mu.Unlock()
+}
+
+// export sends all spans to the OTLP/HTTP endpoint. Spans that were not finished end now.
+func (t *tracer) export(ctx context.Context) error {
+	body, err := json.Marshal(t.otlpRequest(time.Now()))
+	if err != nil {
+		return errors.Join(errors.New("failed to encode spans"), err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, OTEL_EXPORT_TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(timeoutCtx, http.MethodPost, t.endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return errors.Join(errors.New("failed to create request"), err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Join(errors.New("failed to send spans"), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// otlpRequest builds the ExportTraceServiceRequest in the OTLP json encoding
+func (t *tracer) otlpRequest(now time.Time) map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]map[string]interface{}, 0, len(t.spans))
+	for _, span := range t.spans {
+		end := span.end
+		if end.IsZero() {
+			end = now
+		}
+
+		// 1 is ok, 2 is error
+		status := map[string]interface{}{"code": 1}
+		if span.err != nil {
+			status = map[string]interface{}{"code": 2, "message": span.err.Error()}
+		}
+
+		encoded := map[string]interface{}{
+			"traceId":           hex.EncodeToString(t.traceId[:]),
+			"spanId":            hex.EncodeToString(span.spanId[:]),
+			"name":              span.name,
+			"kind":              1, // internal
+			"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.attrs),
+			"status":            status,
+		}
+		if span.parentId != nil {
+			encoded["parentSpanId"] = hex.EncodeToString(span.parentId[:])
+		}
+		spans = append(spans, encoded)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{"service.name": OTEL_SERVICE_NAME, "service.version": Version}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "rocketpoolArbitrage"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func otlpAttributes(attrs map[string]interface{}) []interface{} {
+	encoded := make([]interface{}, 0, len(attrs))
+	for key, value := range attrs {
+		var anyValue map[string]interface{}
+		switch v := value.(type) {
+		case bool:
+			anyValue = map[string]interface{}{"boolValue": v}
+		case int:
+			// int64 values are strings in the OTLP json encoding
+			anyValue = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case uint64:
+			anyValue = map[string]interface{}{"intValue": strconv.FormatUint(v, 10)}
+		case float64:
+			anyValue = map[string]interface{}{"doubleValue": v}
+		default:
+			anyValue = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+
+		encoded = append(encoded, map[string]interface{}{"key": key, "value": anyValue})
+	}
+
+	return encoded
+}
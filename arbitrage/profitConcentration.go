@@ -0,0 +1,84 @@
+package arbitrage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+)
+
+const PROFIT_CONCENTRATION_WARN_PCT = 80
+
+// warnProfitConcentration prints a warning if a single minipool contributes most of the expected profit.
+// The profit of the arbitrage scales with the ETH sent to rETH, so each minipool's share of it is used
+// as its share of the profit. Advisory only, errors are logged.
+func warnProfitConcentration(ctx context.Context, logger *slog.Logger, dataIn *DataIn, result *BuildResult) {
+	minipools := result.IncludedMinipools
+	if len(minipools) < 2 || result.ExpectedProfit == nil {
+		return
+	}
+
+	shares, err := CalculateMinipoolShares(ctx, logger, dataIn.Client, minipools, dataIn.Ratelimit)
+	if err != nil {
+		logger.Warn("failed to calculate per minipool profit share", slog.String("error", err.Error()))
+		return
+	}
+
+	top, topPct := profitConcentration(shares)
+	logger.Debug("profit concentration", slog.String("minipool", shares[top].Address.Hex()), slog.Float64("pct", topPct))
+	if topPct <= PROFIT_CONCENTRATION_WARN_PCT {
+		return
+	}
+
+	// distribute txs are in the same order as the minipools
+	otherProfit := new(big.Int)
+	otherFees := new(big.Int)
+	txs := result.Bundle.Transactions()
+	for i, share := range shares {
+		if i == top {
+			continue
+		}
+
+		profit := minipoolProfit(result.ExpectedProfit, share.RethShare, result.RethShare)
+		otherProfit.Add(otherProfit, profit)
+		if i < len(txs) {
+			otherFees.Add(otherFees, txs[i].Cost())
+		}
+	}
+
+	fmt.Print(colorOrange)
+	fmt.Printf("Warning: minipool %s contributes %.1f%% of the expected profit.\n", shares[top].Address.Hex(), topPct)
+	fmt.Printf("The other %d minipools add about %.6f ETH profit for up to %.6f ETH of distribute fees. Consider distributing them later.\n",
+		len(shares)-1,
+		weiToEth(otherProfit),
+		weiToEth(otherFees),
+	)
+	fmt.Print(colorReset, "\n")
+}
+
+// profitConcentration returns the index of the minipool with the largest rETH share and its percentage of the total
+func profitConcentration(shares []MinipoolShare) (int, float64) {
+	total := new(big.Int)
+	top := 0
+	for i, share := range shares {
+		total.Add(total, share.RethShare)
+		if share.RethShare.Cmp(shares[top].RethShare) > 0 {
+			top = i
+		}
+	}
+
+	if total.Sign() == 0 {
+		return top, 0
+	}
+
+	pct, _ := new(big.Float).Quo(new(big.Float).SetInt(shares[top].RethShare), new(big.Float).SetInt(total)).Float64()
+	return top, pct * 100
+}
+
+func minipoolProfit(expectedProfit, minipoolRethShare, totalRethShare *big.Int) *big.Int {
+	if totalRethShare == nil || totalRethShare.Sign() == 0 {
+		return big.NewInt(0)
+	}
+
+	return new(big.Int).Div(new(big.Int).Mul(expectedProfit, minipoolRethShare), totalRethShare)
+}
@@ -0,0 +1,101 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/big"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// the arbitrage contract reverts below 95% of the expected profit, a lower balance change means the profit went elsewhere
+const PROFIT_RECIPIENT_TOLERANCE_PCT = 5
+
+var (
+	// not a real account, the probe code is only injected into the simulation
+	balanceProbeAddress = common.HexToAddress("0x00000000000000000000000000000000000bA1a0")
+	// PUSH1 0 CALLDATALOAD BALANCE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	balanceProbeCode = hexutil.Bytes{0x60, 0x00, 0x35, 0x31, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+)
+
+// verifyProfitRecipient simulates the bundle on the rpc and reads the receiver balance right before and after the
+// arbitrage tx. The calls pay no gas in the simulation, so the difference is the profit sent to the receiver.
+// Fails if it is below the expected profit minus the tolerance.
+func verifyProfitRecipient(ctx context.Context, logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, arbitrageTx *types.Transaction, expectedProfit *big.Int) error {
+	stateBlock, err := dataIn.Client.BlockNumber(ctx)
+	if err != nil {
+		return errors.Join(errors.New("failed to get block number"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	probe := map[string]interface{}{
+		"to":    balanceProbeAddress,
+		"input": hexutil.Bytes(common.LeftPadBytes(dataIn.ReceiverAddress.Bytes(), 32)),
+	}
+
+	calls := []map[string]interface{}{}
+	arbitrageIndex := -1
+	for _, tx := range bundle.Transactions() {
+		call, _, err := txCall(tx)
+		if err != nil {
+			return err
+		}
+
+		if tx == arbitrageTx {
+			arbitrageIndex = len(calls) + 1
+			calls = append(calls, probe, call, probe)
+			continue
+		}
+		calls = append(calls, call)
+	}
+	if arbitrageIndex < 0 {
+		return errors.New("arbitrage tx is not part of the bundle")
+	}
+
+	overrides := StateOverride{}
+	maps.Copy(overrides, dataIn.StateOverride)
+	overrides[balanceProbeAddress] = OverrideAccount{Code: &balanceProbeCode}
+
+	results, err := simulateCalls(ctx, dataIn, overrides, calls, stateBlock)
+	if err != nil {
+		return err
+	}
+
+	before, after := results[arbitrageIndex-1], results[arbitrageIndex+1]
+	if before.Status == 0 || after.Status == 0 || len(before.ReturnData) != 32 || len(after.ReturnData) != 32 {
+		return errors.New("failed to read the receiver balance in the simulation")
+	}
+	if results[arbitrageIndex].Status == 0 {
+		return errors.New("arbitrage tx reverted in the rpc simulation")
+	}
+
+	received := new(big.Int).Sub(new(big.Int).SetBytes(after.ReturnData), new(big.Int).SetBytes(before.ReturnData))
+	minimum := new(big.Int).Div(new(big.Int).Mul(expectedProfit, big.NewInt(100-PROFIT_RECIPIENT_TOLERANCE_PCT)), big.NewInt(100))
+	logger.Debug("profit recipient check",
+		slog.String("receiver", dataIn.ReceiverAddress.Hex()),
+		slog.String("received", received.String()),
+		slog.String("expectedProfit", expectedProfit.String()),
+		slog.Uint64("stateBlock", stateBlock),
+	)
+
+	if received.Cmp(minimum) < 0 {
+		return fmt.Errorf("%w: receiver %s gains %s ETH from the arbitrage tx in the simulation, expected %s ETH (at least %s ETH), check the receiver and the arbitrage contract",
+			ErrProfitRecipientMismatch,
+			dataIn.ReceiverAddress.Hex(),
+			weiToEthString(received),
+			weiToEthString(expectedProfit),
+			weiToEthString(minimum),
+		)
+	}
+
+	return nil
+}
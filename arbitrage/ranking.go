@@ -0,0 +1,124 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	uniswap "rocketpoolArbitrage/uniswapContracts"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MinipoolScore is the estimated net profit of adding a single minipool to the bundle
+type MinipoolScore struct {
+	Address         common.Address
+	RethShare       *big.Int
+	ExpectedProfit  float64 // ETH, rETH share times the current discount
+	DistributeFee   float64 // ETH, gas limit of the distribute call at the current fees
+	NetProfitPerGas float64 // gwei per gas
+}
+
+// applyTopMinipools ranks the minipools by estimated net profit per gas and keeps the best dataIn.Top.
+// The discount is taken from the main uniswap pool against the protocol rate, this is an estimate for
+// ranking only, the real profit is calculated when the bundle is built.
+func applyTopMinipools(ctx context.Context, logger *slog.Logger, dataIn DataIn, baseGas, tipGas *big.Int, gasLimits map[common.Address]uint64) (included, deferred []common.Address, err error) {
+	if dataIn.Top == 0 {
+		return dataIn.MinipoolAddresses, nil, nil
+	}
+
+	shares, err := CalculateMinipoolShares(ctx, logger, dataIn.Client, dataIn.MinipoolAddresses, dataIn.Ratelimit)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("failed to calculate minipool shares"), err)
+	}
+
+	discount, err := estimatePoolDiscount(ctx, dataIn)
+	if err != nil {
+		return nil, nil, err
+	}
+	gasPrice := new(big.Int).Add(baseGas, tipGas)
+
+	scores := scoreMinipools(shares, discount, gasPrice, gasLimits)
+
+	selected := map[common.Address]bool{}
+	for i, score := range scores {
+		if i < dataIn.Top {
+			selected[score.Address] = true
+		}
+	}
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Printf("Minipools ranked by estimated net profit per gas (discount %.4f%%), building the top %d:\n", discount*100, dataIn.Top)
+		fmt.Printf("    %-4s %-42s %12s %12s %12s %12s %14s\n", "Rank", "Minipool", "ETH to rETH", "Profit", "Fee", "Net", "Net/Gas (gwei)")
+		for i, score := range scores {
+			marker := " "
+			if selected[score.Address] {
+				marker = "*"
+			}
+			fmt.Printf("  %s %-4d %-42s %12.6f %12.6f %12.6f %12.6f %14.4f\n",
+				marker,
+				i+1,
+				score.Address.Hex(),
+				weiToEth(score.RethShare),
+				score.ExpectedProfit,
+				score.DistributeFee,
+				score.ExpectedProfit-score.DistributeFee,
+				score.NetProfitPerGas,
+			)
+		}
+		fmt.Println()
+	}
+
+	// keep the input order, like the gas budget does
+	for _, minipool := range dataIn.MinipoolAddresses {
+		if selected[minipool] {
+			included = append(included, minipool)
+		} else {
+			deferred = append(deferred, minipool)
+		}
+	}
+
+	return included, deferred, nil
+}
+
+// estimatePoolDiscount returns the discount of the main uniswap pool to the protocol rate as a fraction
+func estimatePoolDiscount(ctx context.Context, dataIn DataIn) (float64, error) {
+	exchangeRate, err := GetExchangeRateSource(ctx, dataIn.Client, dataIn.NetworkId, dataIn.Ratelimit)
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to get exchange rate source"), err)
+	}
+
+	poolPrice, err := uniswap.GetPoolPrice(ctx, dataIn.Client, common.HexToAddress(uniswap.PoolA), dataIn.Ratelimit)
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to get pool price"), err)
+	}
+
+	protocolRate := weiToEth(exchangeRate.Rate)
+	return (protocolRate - poolPrice) / protocolRate, nil
+}
+
+// scoreMinipools returns the scores sorted from the best to the worst net profit per gas,
+// using the gas limit of each minipool's distribute call
+func scoreMinipools(shares []MinipoolShare, discount float64, gasPrice *big.Int, gasLimits map[common.Address]uint64) []MinipoolScore {
+	scores := make([]MinipoolScore, len(shares))
+	for i, share := range shares {
+		gas := gasLimits[share.Address]
+		fee := weiToEth(new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas)))
+		profit := weiToEth(share.RethShare) * discount
+		scores[i] = MinipoolScore{
+			Address:         share.Address,
+			RethShare:       share.RethShare,
+			ExpectedProfit:  profit,
+			DistributeFee:   fee,
+			NetProfitPerGas: (profit - fee) * 1e9 / float64(gas),
+		}
+	}
+
+	sort.SliceStable(scores, func(a, b int) bool {
+		return scores[a].NetProfitPerGas > scores[b].NetProfitPerGas
+	})
+
+	return scores
+}
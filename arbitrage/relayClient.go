@@ -0,0 +1,132 @@
+package arbitrage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+)
+
+const RELAY_CHECK_TIMEOUT = 5 * time.Second
+
+// Version of the tool, set at build time with -ldflags "-X rocketpoolArbitrage/arbitrage.Version=v1.2.3"
+var Version = "dev"
+
+// DefaultClientTag is sent as User-Agent to the relay if no other tag is configured
+func DefaultClientTag() string {
+	return "RocketpoolExitArbitrage/" + Version
+}
+
+// SetRelayClientTag identifies all relay requests with the given User-Agent.
+// The flashbots client does not expose its http client and uses the default transport,
+// so the tag is added there. Only requests carrying a flashbots signature are changed,
+// regular eth1 rpc calls keep their User-Agent. Must be called before any client is created.
+func SetRelayClientTag(tag string) {
+	if tag == "" {
+		return
+	}
+
+	http.DefaultTransport = &relayHeaderTransport{
+		base:  http.DefaultTransport,
+		name:  "User-Agent",
+		value: tag,
+	}
+}
+
+// SetRelayUrl replaces the flashbots relay of the network, e.g. with a self-hosted relay proxy that speaks the
+// flashbots JSON-RPC. Must be called before the flashbots client is created.
+func SetRelayUrl(networkId uint64, relayUrl string) error {
+	parsed, err := url.Parse(relayUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid relay url: %s", relayUrl)
+	}
+
+	flashbots_client.FlashbotsUrlPerNetwork[networkId] = relayUrl
+	return nil
+}
+
+// SetRelayAuthHeader adds the header, given as "Name: value", to all signed relay requests. Like the client tag
+// it is added in the default transport. Must be called before any client is created.
+func SetRelayAuthHeader(header string) error {
+	name, value, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+
+	http.DefaultTransport = &relayHeaderTransport{
+		base:  http.DefaultTransport,
+		name:  name,
+		value: value,
+	}
+	return nil
+}
+
+func parseHeader(header string) (string, string, error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+	if !ok || name == "" || value == "" || strings.ContainsAny(name, " \t") {
+		return "", "", errors.New("header must be given as \"Name: value\"")
+	}
+
+	return name, value, nil
+}
+
+// CheckRelay sends an unsigned JSON-RPC request to the relay to fail early if it is unreachable or rejects the auth
+// header. Any JSON-RPC answer counts as reachable, a proxy may not implement the method.
+func CheckRelay(relayUrl, authHeader string) error {
+	req, err := http.NewRequest("POST", relayUrl, bytes.NewBufferString(`{"jsonrpc":"2.0","id":0,"method":"eth_blockNumber","params":[]}`))
+	if err != nil {
+		return errors.Join(errors.New("failed to create relay request"), err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authHeader != "" {
+		name, value, err := parseHeader(authHeader)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(name, value)
+	}
+
+	client := http.Client{Timeout: RELAY_CHECK_TIMEOUT}
+	response, err := client.Do(req)
+	if err != nil {
+		return errors.Join(fmt.Errorf("relay %s is not reachable", relayUrl), err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("relay %s rejected the request with status %d, check the auth header", relayUrl, response.StatusCode)
+	}
+	if response.StatusCode >= 500 {
+		return fmt.Errorf("relay %s answered with status %d", relayUrl, response.StatusCode)
+	}
+
+	return nil
+}
+
+// relayHeaderTransport sets a header on requests carrying a flashbots signature
+type relayHeaderTransport struct {
+	base  http.RoundTripper
+	name  string
+	value string
+}
+
+func (t *relayHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("X-Flashbots-Signature") == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrip must not modify the original request
+	tagged := req.Clone(req.Context())
+	tagged.Header.Set(t.name, t.value)
+
+	return t.base.RoundTrip(tagged)
+}
@@ -0,0 +1,111 @@
+package arbitrage
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	REORG_POLL_INTERVAL = 2 * time.Second
+	REORG_HISTORY       = 64 // block hashes kept to compare new heads against
+)
+
+// headTracker remembers the canonical block hashes seen so far to tell if a new head reorged the chain
+type headTracker struct {
+	hashes map[uint64]common.Hash
+}
+
+func newHeadTracker() *headTracker {
+	return &headTracker{hashes: map[uint64]common.Hash{}}
+}
+
+// observe records the head and reports whether it replaced a block seen before, either at its own height
+// or as its parent. Blocks above the new head are dropped, they are no longer canonical.
+func (t *headTracker) observe(number uint64, hash, parentHash common.Hash) bool {
+	if known, ok := t.hashes[number]; ok && known == hash {
+		return false
+	}
+
+	_, reorged := t.hashes[number]
+	if number > 0 {
+		if known, ok := t.hashes[number-1]; ok && known != parentHash {
+			reorged = true
+		}
+	}
+
+	for height := range t.hashes {
+		if height > number || height+REORG_HISTORY < number {
+			delete(t.hashes, height)
+		}
+	}
+	t.hashes[number] = hash
+	if number > 0 {
+		t.hashes[number-1] = parentHash
+	}
+
+	return reorged
+}
+
+// watchReorgs follows the chain head while the bundle waits for inclusion. On a reorg the bundle is sent again
+// for the block after the new head, in case it was included in a block that is no longer canonical.
+// The returned channel is closed once ctx is done.
+func watchReorgs(ctx context.Context, logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		tracker := newHeadTracker()
+		ticker := time.NewTicker(REORG_POLL_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			header, err := dataIn.Client.HeaderByNumber(ctx, nil)
+			if dataIn.Ratelimit > 0 {
+				time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				logger.Debug("failed to get chain head for the reorg check", slog.String("error", err.Error()))
+			} else if tracker.observe(header.Number.Uint64(), header.Hash(), header.ParentHash) {
+				retargetBundle(logger, dataIn, bundle, header)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return done
+}
+
+func retargetBundle(logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle, head *types.Header) {
+	target := head.Number.Uint64() + 1
+	logger.Warn("chain reorg detected while waiting for inclusion",
+		slog.Uint64("head", head.Number.Uint64()),
+		slog.String("headHash", head.Hash().Hex()),
+		slog.Uint64("retargetBlock", target),
+	)
+
+	retargeted := bundle.Copy()
+	err := retargeted.SetTargetBlockNumber(target)
+	if err != nil {
+		logger.Warn("failed to retarget bundle after reorg", slog.String("error", err.Error()))
+		return
+	}
+
+	_, _, err = dataIn.FbClient.SendBundle(retargeted)
+	if err != nil {
+		logger.Warn("failed to send retargeted bundle after reorg", slog.Uint64("targetBlock", target), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,217 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// minimal RocketNodeStaking ABI, only the view functions needed for the RPL advisory
+const RocketNodeStakingABI = `[{"inputs":[{"internalType":"address","name":"_nodeAddress","type":"address"}],"name":"getNodeRPLStake","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_nodeAddress","type":"address"}],"name":"getNodeMinimumRPLStake","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_nodeAddress","type":"address"}],"name":"getNodeMaximumRPLStake","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_nodeAddress","type":"address"}],"name":"getNodeETHMatched","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_nodeAddress","type":"address"}],"name":"getNodeETHProvided","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+
+type RplImpact struct {
+	Node     common.Address
+	RplStake *big.Int
+
+	MinimumBefore *big.Int // RPL stake required for rewards
+	MinimumAfter  *big.Int
+	MaximumBefore *big.Int // RPL stake that earns rewards at most
+	MaximumAfter  *big.Int
+
+	ActiveAfter bool // false if no borrowed ETH is left after the distribution
+}
+
+// warnRplImpact prints an advisory per node if distributing its minipools affects its RPL rewards.
+// Distributing an exited minipool finalises it, which removes its borrowed and bonded ETH from the node.
+// The minimum RPL stake and the maximum stake earning rewards both scale with these, so the
+// minimum can only drop while the capped stake may shrink. Advisory only, errors are logged.
+func warnRplImpact(ctx context.Context, logger *slog.Logger, dataIn *DataIn, minipools []common.Address) {
+	nodes, nodeMinipools, err := groupMinipoolsByNode(ctx, dataIn, minipools)
+	if err != nil {
+		logger.Warn("failed to get the minipool nodes for the RPL advisory", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, node := range nodes {
+		impact, err := getRplImpact(ctx, dataIn, node, nodeMinipools[node])
+		if err != nil {
+			logger.Warn("failed to get the RPL impact", slog.String("node", node.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+		logger.Debug("rpl impact",
+			slog.String("node", node.Hex()),
+			slog.String("rplStake", impact.RplStake.String()),
+			slog.String("minimumAfter", impact.MinimumAfter.String()),
+			slog.String("maximumAfter", impact.MaximumAfter.String()),
+		)
+
+		printRplImpact(impact)
+	}
+}
+
+func printRplImpact(impact *RplImpact) {
+	if impact.RplStake.Sign() == 0 {
+		return
+	}
+	stake := weiToEth(impact.RplStake)
+
+	if !impact.ActiveAfter {
+		fmt.Print(colorOrange)
+		fmt.Printf("Note: node %s has no active minipools left after this distribution, its %.2f RPL stake no longer earns RPL rewards.\n", impact.Node.Hex(), stake)
+		fmt.Print(colorReset, "\n")
+		return
+	}
+
+	if impact.RplStake.Cmp(impact.MinimumAfter) < 0 {
+		fmt.Print(colorOrange)
+		fmt.Printf("Note: node %s has %.2f RPL staked, below the minimum of %.2f RPL, it is not eligible for RPL rewards.\n",
+			impact.Node.Hex(),
+			stake,
+			weiToEth(impact.MinimumAfter),
+		)
+		fmt.Print(colorReset, "\n")
+		return
+	}
+
+	if impact.RplStake.Cmp(impact.MinimumBefore) < 0 {
+		fmt.Printf("Note: node %s is back above the minimum RPL stake after this distribution (%.2f of %.2f RPL).\n\n",
+			impact.Node.Hex(),
+			stake,
+			weiToEth(impact.MinimumAfter),
+		)
+	}
+
+	// only warn if the distribution is what pushes the stake over the cap
+	if impact.RplStake.Cmp(impact.MaximumAfter) > 0 && impact.MaximumAfter.Cmp(impact.MaximumBefore) < 0 {
+		// the part above the previous cap did not earn rewards before either
+		earning := impact.RplStake
+		if earning.Cmp(impact.MaximumBefore) > 0 {
+			earning = impact.MaximumBefore
+		}
+		overCap := new(big.Int).Sub(earning, impact.MaximumAfter)
+
+		fmt.Print(colorOrange)
+		fmt.Printf("Note: distributing lowers the RPL stake of node %s that earns rewards from %.2f to %.2f RPL, %.2f RPL more of the %.2f RPL stake no longer earns rewards.\n",
+			impact.Node.Hex(),
+			weiToEth(impact.MaximumBefore),
+			weiToEth(impact.MaximumAfter),
+			weiToEth(overCap),
+			stake,
+		)
+		fmt.Print(colorReset, "\n")
+	}
+}
+
+// getRplImpact reads the RPL stake limits of the node and scales them by the ETH that is removed from the node
+// once the given minipools are finalised
+func getRplImpact(ctx context.Context, dataIn *DataIn, node common.Address, minipools []common.Address) (*RplImpact, error) {
+	stakingAddress, err := getRocketpoolContractAddress(ctx, dataIn.Client, dataIn.NetworkId, "rocketNodeStaking", dataIn.Ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get node staking address"), err)
+	}
+
+	stakingABI, err := abi.JSON(strings.NewReader(RocketNodeStakingABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get node staking ABI"), err)
+	}
+
+	results := map[string]*big.Int{}
+	for _, method := range []string{"getNodeRPLStake", "getNodeMinimumRPLStake", "getNodeMaximumRPLStake", "getNodeETHMatched", "getNodeETHProvided"} {
+		values, err := callViewFunction(ctx, dataIn.Client, stakingABI, stakingAddress, dataIn.Ratelimit, method, node)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := values[0].(*big.Int)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %s output", method)
+		}
+		results[method] = value
+	}
+
+	borrowedRemoved := new(big.Int)
+	bondedRemoved := new(big.Int)
+	for _, minipoolAddress := range minipools {
+		minipoolInstance, err := minipoolDelegate.NewMinipoolDelegate(minipoolAddress, dataIn.Client)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to create minipool instance", minipoolAddress), err)
+		}
+
+		borrowed, err := minipoolInstance.GetUserDepositBalance(nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get user deposit balance", minipoolAddress), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		bonded, err := minipoolInstance.GetNodeDepositBalance(nil)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("%s: failed to get node deposit balance", minipoolAddress), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		borrowedRemoved.Add(borrowedRemoved, borrowed)
+		bondedRemoved.Add(bondedRemoved, bonded)
+	}
+
+	borrowedBefore := results["getNodeETHMatched"]
+	bondedBefore := results["getNodeETHProvided"]
+	borrowedAfter := new(big.Int).Sub(borrowedBefore, borrowedRemoved)
+	bondedAfter := new(big.Int).Sub(bondedBefore, bondedRemoved)
+
+	return &RplImpact{
+		Node:          node,
+		RplStake:      results["getNodeRPLStake"],
+		MinimumBefore: results["getNodeMinimumRPLStake"],
+		MinimumAfter:  scaleStake(results["getNodeMinimumRPLStake"], borrowedAfter, borrowedBefore),
+		MaximumBefore: results["getNodeMaximumRPLStake"],
+		MaximumAfter:  scaleStake(results["getNodeMaximumRPLStake"], bondedAfter, bondedBefore),
+		ActiveAfter:   borrowedAfter.Sign() > 0,
+	}, nil
+}
+
+// scaleStake scales an RPL stake limit proportionally to the change of the ETH it is based on
+func scaleStake(stake, ethAfter, ethBefore *big.Int) *big.Int {
+	if ethBefore.Sign() == 0 || ethAfter.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	return new(big.Int).Div(new(big.Int).Mul(stake, ethAfter), ethBefore)
+}
+
+// groupMinipoolsByNode returns the distinct node addresses in the order they first appear and their minipools
+func groupMinipoolsByNode(ctx context.Context, dataIn *DataIn, minipools []common.Address) ([]common.Address, map[common.Address][]common.Address, error) {
+	nodes := []common.Address{}
+	nodeMinipools := map[common.Address][]common.Address{}
+	for _, minipoolAddress := range minipools {
+		minipoolInstance, err := minipoolDelegate.NewMinipoolDelegate(minipoolAddress, dataIn.Client)
+		if err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("%s: failed to create minipool instance", minipoolAddress), err)
+		}
+
+		node, err := GetMinipoolNodeAddress(ctx, minipoolInstance)
+		if err != nil {
+			return nil, nil, errors.Join(fmt.Errorf("%s: failed to get node address", minipoolAddress), err)
+		}
+		if dataIn.Ratelimit > 0 {
+			time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+		}
+
+		if _, ok := nodeMinipools[node]; !ok {
+			nodes = append(nodes, node)
+		}
+		nodeMinipools[node] = append(nodeMinipools[node], minipoolAddress)
+	}
+
+	return nodes, nodeMinipools, nil
+}
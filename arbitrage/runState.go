@@ -0,0 +1,95 @@
+package arbitrage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// runState records the outcome of each bundle, so a failed batch run can be resumed
+type runState struct {
+	Distributed []common.Address `json:"distributed"`
+	Failed      []common.Address `json:"failed"`
+	UpdatedAt   string           `json:"updatedAt"`
+}
+
+func loadRunState(path string) (*runState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &runState{}, nil
+	}
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read state file"), err)
+	}
+
+	var state runState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to parse state file"), err)
+	}
+
+	return &state, nil
+}
+
+// updateRunState marks the minipools of a finished bundle as distributed or failed and writes the state file.
+// A dry run does not change anything on-chain and is not recorded.
+func updateRunState(path string, report *RunReport) error {
+	if report.DryRun {
+		return nil
+	}
+
+	state, err := loadRunState(path)
+	if err != nil {
+		return err
+	}
+
+	// drop previous entries of these minipools, the latest outcome wins
+	state.Distributed = withoutAddresses(state.Distributed, report.Minipools)
+	state.Failed = withoutAddresses(state.Failed, report.Minipools)
+
+	if report.Included || report.DistributedElsewhere {
+		state.Distributed = append(state.Distributed, report.Minipools...)
+	} else {
+		state.Failed = append(state.Failed, report.Minipools...)
+	}
+	state.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode state"), err)
+	}
+
+	// write to a temp file first, an interrupted run must not leave a broken state file behind
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, append(data, '\n'), 0644)
+	if err != nil {
+		return errors.Join(errors.New("failed to write state file"), err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return errors.Join(errors.New("failed to write state file"), err)
+	}
+
+	return nil
+}
+
+// withoutAddresses returns the addresses that are not in remove, keeping their order
+func withoutAddresses(addresses, remove []common.Address) []common.Address {
+	removeSet := make(map[common.Address]bool, len(remove))
+	for _, address := range remove {
+		removeSet[address] = true
+	}
+
+	out := []common.Address{}
+	for _, address := range addresses {
+		if !removeSet[address] {
+			out = append(out, address)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,154 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// SafeBatch is the batch file format of the Safe{Wallet} Transaction Builder app
+type SafeBatch struct {
+	Version      string        `json:"version"`
+	ChainId      string        `json:"chainId"`
+	CreatedAt    int64         `json:"createdAt"`
+	Meta         SafeBatchMeta `json:"meta"`
+	Transactions []SafeBatchTx `json:"transactions"`
+}
+
+type SafeBatchMeta struct {
+	Name                   string `json:"name"`
+	Description            string `json:"description"`
+	CreatedFromSafeAddress string `json:"createdFromSafeAddress"`
+}
+
+type SafeBatchTx struct {
+	To    common.Address `json:"to"`
+	Value string         `json:"value"`
+	Data  string         `json:"data"`
+}
+
+// BuildSafeBatch creates a Transaction Builder batch of distributeBalance calls to be executed by the Safe. The Safe
+// must be the withdrawal address of the node, which may distribute like the node address itself, and all minipools
+// must be staking V3 minipools of the node. The distribution is a regular Safe tx, there is no arbitrage bundle.
+func BuildSafeBatch(ctx context.Context, client *ethclient.Client, networkId uint64, safe, nodeAddress common.Address, minipools []common.Address, ratelimit int) (*SafeBatch, error) {
+	if len(minipools) == 0 {
+		return nil, errors.New("no minipools to distribute")
+	}
+
+	withdrawalAddress, err := getWithdrawalAddress(ctx, client, networkId, nodeAddress, ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get withdrawal address"), err)
+	}
+	if withdrawalAddress != safe {
+		return nil, fmt.Errorf("the withdrawal address of node %s is %s, not the safe %s", nodeAddress.Hex(), withdrawalAddress.Hex(), safe.Hex())
+	}
+
+	minipoolAbi, err := abi.JSON(strings.NewReader(minipoolDelegate.MinipoolDelegateABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool ABI"), err)
+	}
+
+	callData, err := minipoolAbi.Pack("distributeBalance", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack function data: %v", err)
+	}
+
+	batch := &SafeBatch{
+		Version:   "1.0",
+		ChainId:   strconv.FormatUint(networkId, 10),
+		CreatedAt: time.Now().UnixMilli(),
+		Meta: SafeBatchMeta{
+			Name:                   "Distribute minipools",
+			Description:            fmt.Sprintf("distributeBalance of %d minipools of node %s", len(minipools), nodeAddress.Hex()),
+			CreatedFromSafeAddress: safe.Hex(),
+		},
+	}
+
+	for _, minipoolAddress := range minipools {
+		err = verifySafeMinipool(ctx, client, nodeAddress, minipoolAddress, ratelimit)
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Transactions = append(batch.Transactions, SafeBatchTx{
+			To:    minipoolAddress,
+			Value: "0",
+			Data:  hexutil.Encode(callData),
+		})
+	}
+
+	return batch, nil
+}
+
+func verifySafeMinipool(ctx context.Context, client *ethclient.Client, nodeAddress, minipoolAddress common.Address, ratelimit int) error {
+	minipoolInstance, err := minipoolDelegate.NewMinipoolDelegate(minipoolAddress, client)
+	if err != nil {
+		return errors.Join(fmt.Errorf("%s: failed to create minipool instance", minipoolAddress), err)
+	}
+
+	version, err := GetMinipoolDelegateVersion(ctx, minipoolInstance)
+	if err != nil {
+		return errors.Join(fmt.Errorf("%s: failed to get minipool version", minipoolAddress), err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+	if version != 3 {
+		return fmt.Errorf("%s: only minipool V3 is supported", minipoolAddress)
+	}
+
+	status, err := GetMinipoolStatus(ctx, minipoolInstance)
+	if err != nil {
+		return errors.Join(fmt.Errorf("%s: failed to get minipool status", minipoolAddress), err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+	if status != uint8(2) {
+		return fmt.Errorf("%s: minipool is not staking", minipoolAddress)
+	}
+
+	owner, err := GetMinipoolNodeAddress(ctx, minipoolInstance)
+	if err != nil {
+		return errors.Join(fmt.Errorf("%s: failed to get node address", minipoolAddress), err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+	if owner != nodeAddress {
+		return fmt.Errorf("%s: minipool belongs to node %s", minipoolAddress, owner.Hex())
+	}
+
+	return nil
+}
+
+// WriteSafeBatch writes the batch as JSON, a path of "-" writes to stdout
+func WriteSafeBatch(path string, batch *SafeBatch) error {
+	encoded, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed to encode safe batch"), err)
+	}
+	encoded = append(encoded, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(encoded)
+	} else {
+		err = os.WriteFile(path, encoded, 0644)
+	}
+	if err != nil {
+		return errors.Join(errors.New("failed to write safe batch"), err)
+	}
+
+	return nil
+}
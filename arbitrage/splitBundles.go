@@ -0,0 +1,118 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// executeSplitDistribute splits the minipools into bundles of dataIn.BundleSize and sends them one after another.
+// The minipools sending the most ETH to rETH go first, so with dataIn.MaxBundles only the most profitable
+// bundles are sent and the rest is deferred to a later run.
+func executeSplitDistribute(ctx context.Context, logger *slog.Logger, dataIn *DataIn) ([]*RunReport, error) {
+	shares, err := CalculateMinipoolShares(ctx, logger, dataIn.Client, dataIn.MinipoolAddresses, dataIn.Ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to calculate minipool shares"), err)
+	}
+
+	groups, deferred := splitMinipools(shares, dataIn.BundleSize, dataIn.MaxBundles)
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		fmt.Printf("Splitting %d minipools into %d bundles of up to %d minipools.\n", len(dataIn.MinipoolAddresses), len(groups), dataIn.BundleSize)
+		printDeferredMinipools(deferred)
+		fmt.Println()
+	}
+
+	reports := make([]*RunReport, 0, len(groups))
+	for i, group := range groups {
+		if logger.Enabled(ctx, slog.LevelInfo) {
+			fmt.Printf("Bundle %d of %d:\n", i+1, len(groups))
+			printSessionTotal(dataIn)
+		}
+
+		groupData := *dataIn
+		groupData.MinipoolAddresses = group
+
+		report, err := executeDistributeRun(ctx, logger, &groupData)
+		reports = append(reports, report)
+		if errors.Is(err, ErrMaxTotalValueReached) {
+			// not a failure, the remaining minipools are left for a later run
+			fmt.Println(err)
+			for _, remaining := range groups[i:] {
+				deferred = append(deferred, remaining...)
+			}
+			printDeferredMinipools(deferred)
+			return reports, nil
+		}
+		if err != nil {
+			// the remaining bundles were not sent either
+			for _, remaining := range groups[i+1:] {
+				deferred = append(deferred, remaining...)
+			}
+			printDeferredMinipools(deferred)
+			return reports, errors.Join(fmt.Errorf("bundle %d of %d failed", i+1, len(groups)), err)
+		}
+	}
+
+	if logger.Enabled(ctx, slog.LevelInfo) {
+		printSessionTotal(dataIn)
+		printDeferredMinipools(deferred)
+	}
+
+	return reports, nil
+}
+
+// splitMinipools orders the minipools by rETH share and cuts them into groups of bundleSize.
+// Everything after maxBundles groups is returned as deferred.
+func splitMinipools(shares []MinipoolShare, bundleSize, maxBundles int) (groups [][]common.Address, deferred []common.Address) {
+	sorted := make([]MinipoolShare, len(shares))
+	copy(sorted, shares)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return sorted[a].RethShare.Cmp(sorted[b].RethShare) > 0
+	})
+
+	for start := 0; start < len(sorted); start += bundleSize {
+		end := min(start+bundleSize, len(sorted))
+
+		group := make([]common.Address, 0, end-start)
+		for _, share := range sorted[start:end] {
+			group = append(group, share.Address)
+		}
+
+		if maxBundles > 0 && len(groups) >= maxBundles {
+			deferred = append(deferred, group...)
+		} else {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, deferred
+}
+
+func printSessionTotal(dataIn *DataIn) {
+	if dataIn.MaxTotalValue == nil {
+		fmt.Printf("Total value at risk so far: %.6f ETH\n", weiToEth(dataIn.session.valueAtRisk))
+		return
+	}
+
+	fmt.Printf("Total value at risk so far: %.6f of %.6f ETH\n", weiToEth(dataIn.session.valueAtRisk), weiToEth(dataIn.MaxTotalValue))
+}
+
+func printDeferredMinipools(deferred []common.Address) {
+	if len(deferred) == 0 {
+		return
+	}
+
+	strs := make([]string, len(deferred))
+	for i, minipool := range deferred {
+		strs[i] = minipool.Hex()
+	}
+
+	fmt.Printf("Deferred %d minipools, distribute them in a later run:\n", len(deferred))
+	fmt.Printf("    --minipools=%s\n", strings.Join(strs, ","))
+}
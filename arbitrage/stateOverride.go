@@ -0,0 +1,168 @@
+package arbitrage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const STATE_OVERRIDE_SIMULATION_TIMEOUT = 30 * time.Second
+
+// StateOverride is the state override set of eth_call, keyed by account
+type StateOverride map[common.Address]OverrideAccount
+
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code      *hexutil.Bytes              `json:"code,omitempty"`
+	Balance   *hexutil.Big                `json:"balance,omitempty"`
+	State     map[common.Hash]common.Hash `json:"state,omitempty"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
+}
+
+// LoadStateOverride parses the overrides from inline JSON or, if value does not start with "{", from the file at value
+func LoadStateOverride(value string) (StateOverride, error) {
+	data := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		var err error
+		data, err = os.ReadFile(value)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to read state override file"), err)
+		}
+	}
+
+	var overrides StateOverride
+	err := json.Unmarshal(data, &overrides)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to parse state override"), err)
+	}
+
+	for address, account := range overrides {
+		if account.State != nil && account.StateDiff != nil {
+			return nil, fmt.Errorf("%s: state and stateDiff cannot both be overridden", address.Hex())
+		}
+	}
+
+	return overrides, nil
+}
+
+type simulatedCall struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type simulatedBlock struct {
+	Calls []simulatedCall `json:"calls"`
+}
+
+// simulateWithStateOverride simulates the bundle with eth_simulateV1 on the eth1 rpc, since the flashbots relay does
+// not accept state overrides. The txs are executed as calls on top of stateBlock, without nonce, fee or signature
+// validation, and the result is returned in the shape of a relay simulation.
+func simulateWithStateOverride(dataIn *DataIn, bundle *flashbots_client.Bundle, stateBlock uint64) (*flashbots_client.SimulationResultBundle, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), STATE_OVERRIDE_SIMULATION_TIMEOUT)
+	defer cancel()
+
+	txs := bundle.Transactions()
+	calls := make([]map[string]interface{}, 0, len(txs))
+	senders := make([]common.Address, 0, len(txs))
+	for _, tx := range txs {
+		call, sender, err := txCall(tx)
+		if err != nil {
+			return nil, false, err
+		}
+		calls = append(calls, call)
+		senders = append(senders, sender)
+	}
+
+	simulated, err := simulateCalls(ctx, dataIn, dataIn.StateOverride, calls, stateBlock)
+	if err != nil {
+		return nil, false, errors.Join(errors.New("state overrides need an rpc that supports eth_simulateV1"), err)
+	}
+
+	result := &flashbots_client.SimulationResultBundle{StateBlockNumber: stateBlock}
+	for index, call := range simulated {
+		tx := txs[index]
+		txResult := flashbots_client.SimulationResultTransaction{
+			FromAddress: senders[index],
+			ToAddress:   *tx.To(),
+			GasUsed:     uint64(call.GasUsed),
+			TxHash:      tx.Hash(),
+			Value:       tx.Value(),
+		}
+
+		if call.Status == 0 {
+			txResult.Error = "execution reverted"
+			if call.Error != nil {
+				txResult.Error = call.Error.Message
+			}
+			txResult.RevertReason, err = abi.UnpackRevert(call.ReturnData)
+			if err != nil {
+				txResult.RevertReason = string(call.ReturnData)
+			}
+			if result.FirstRevert == (common.Hash{}) {
+				result.FirstRevert = tx.Hash()
+			}
+		}
+
+		result.TotalGasUsed += txResult.GasUsed
+		result.Results = append(result.Results, txResult)
+	}
+
+	return result, result.FirstRevert == (common.Hash{}), nil
+}
+
+// txCall turns the signed tx into an eth_simulateV1 call from its sender
+func txCall(tx *types.Transaction) (map[string]interface{}, common.Address, error) {
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, common.Address{}, errors.Join(fmt.Errorf("%s: failed to get tx sender", tx.Hash().Hex()), err)
+	}
+
+	return map[string]interface{}{
+		"from":  sender,
+		"to":    tx.To(),
+		"gas":   hexutil.Uint64(tx.Gas()),
+		"value": (*hexutil.Big)(tx.Value()),
+		"input": hexutil.Bytes(tx.Data()),
+	}, sender, nil
+}
+
+// simulateCalls executes the calls in a single simulated block on top of stateBlock and returns one result per call
+func simulateCalls(ctx context.Context, dataIn *DataIn, overrides StateOverride, calls []map[string]interface{}, stateBlock uint64) ([]simulatedCall, error) {
+	params := map[string]interface{}{
+		"blockStateCalls": []interface{}{
+			map[string]interface{}{
+				"stateOverrides": overrides,
+				"calls":          calls,
+			},
+		},
+		"validation": false,
+	}
+
+	var blocks []simulatedBlock
+	err := dataIn.Client.Client().CallContext(ctx, &blocks, "eth_simulateV1", params, hexutil.Uint64(stateBlock))
+	if err != nil {
+		return nil, errors.Join(errors.New("eth_simulateV1 failed"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+	if len(blocks) != 1 || len(blocks[0].Calls) != len(calls) {
+		return nil, errors.New("unexpected eth_simulateV1 result")
+	}
+
+	return blocks[0].Calls, nil
+}
@@ -0,0 +1,48 @@
+package arbitrage
+
+import (
+	"fmt"
+	"time"
+)
+
+// PhaseTiming is the duration of one phase of a run, a phase repeated by a rebuild is listed again
+type PhaseTiming struct {
+	Phase    string
+	Duration time.Duration
+}
+
+// phaseTimer measures a phase for --timings, time.Since uses the monotonic clock reading of the start.
+// A nil timer is a no-op, timings are only collected if enabled.
+type phaseTimer struct {
+	report *RunReport
+	phase  string
+	start  time.Time
+}
+
+func startPhase(dataIn *DataIn, report *RunReport, phase string) *phaseTimer {
+	if !dataIn.Timings {
+		return nil
+	}
+	return &phaseTimer{report: report, phase: phase, start: time.Now()}
+}
+
+func (t *phaseTimer) stop() {
+	if t == nil {
+		return
+	}
+	t.report.Timings = append(t.report.Timings, PhaseTiming{Phase: t.phase, Duration: time.Since(t.start)})
+}
+
+func printTimings(timings []PhaseTiming) {
+	if len(timings) == 0 {
+		return
+	}
+
+	fmt.Println("Timings:")
+	total := time.Duration(0)
+	for _, timing := range timings {
+		fmt.Printf("    %-18s %10s\n", timing.Phase, timing.Duration.Round(time.Millisecond))
+		total += timing.Duration
+	}
+	fmt.Printf("    %-18s %10s\n\n", "Total", total.Round(time.Millisecond))
+}
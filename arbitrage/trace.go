@@ -0,0 +1,114 @@
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// callFrame is the output of the geth callTracer
+type callFrame struct {
+	Type         string         `json:"type"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Value        *hexutil.Big   `json:"value"`
+	Gas          hexutil.Uint64 `json:"gas"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Input        hexutil.Bytes  `json:"input"`
+	Output       hexutil.Bytes  `json:"output"`
+	Error        string         `json:"error"`
+	RevertReason string         `json:"revertReason"`
+	Calls        []callFrame    `json:"calls"`
+}
+
+// traceBundleTransaction traces a single bundle tx with debug_traceCall on the latest block.
+// The distribute calls preceding the arbitrage tx are not part of that state, so like for the manual
+// Tenderly simulation the rETH contract balance is raised by the ETH they would send if overrideRethBalance is set.
+func traceBundleTransaction(ctx context.Context, dataIn *DataIn, bundle *flashbots_client.Bundle, index int, overrideRethBalance bool, rethShare *big.Int) (*callFrame, error) {
+	txs := bundle.Transactions()
+	if index < 0 || index >= len(txs) {
+		return nil, fmt.Errorf("invalid tx index %d", index)
+	}
+	tx := txs[index]
+
+	// with a fee payer the arbitrage tx is not sent by the node
+	from := *dataIn.NodeAddress
+	if sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+		from = sender
+	}
+
+	callArgs := map[string]interface{}{
+		"from":  from,
+		"to":    tx.To(),
+		"gas":   hexutil.Uint64(tx.Gas()),
+		"value": (*hexutil.Big)(tx.Value()),
+		"input": hexutil.Bytes(tx.Data()),
+	}
+
+	config := map[string]interface{}{
+		"tracer": "callTracer",
+	}
+
+	if overrideRethBalance && rethShare != nil && rethShare.Sign() > 0 {
+		rEthContractAddress, err := GetREthContractAddress(dataIn.NetworkId)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to get rETH contract address"), err)
+		}
+
+		balance, err := dataIn.Client.BalanceAt(ctx, rEthContractAddress, nil)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to get rETH contract balance"), err)
+		}
+
+		config["stateOverrides"] = map[common.Address]interface{}{
+			rEthContractAddress: map[string]interface{}{
+				"balance": (*hexutil.Big)(new(big.Int).Add(balance, rethShare)),
+			},
+		}
+	}
+
+	var frame callFrame
+	err := dataIn.Client.Client().CallContext(ctx, &frame, "debug_traceCall", callArgs, "latest", config)
+	if err != nil {
+		return nil, errors.Join(errors.New("debug_traceCall failed, the rpc needs the debug namespace enabled"), err)
+	}
+
+	return &frame, nil
+}
+
+// printCallFrame prints the call tree, marking failed calls
+func printCallFrame(frame *callFrame, depth int) {
+	indent := strings.Repeat("    ", depth+1)
+
+	value := "0"
+	if frame.Value != nil {
+		value = frame.Value.ToInt().String()
+	}
+
+	selector := ""
+	if len(frame.Input) >= 4 {
+		selector = hexutil.Encode(frame.Input[:4])
+	}
+
+	fmt.Printf("%s%s %s -> %s value: %s gas used: %d %s", indent, frame.Type, frame.From.Hex(), frame.To.Hex(), value, uint64(frame.GasUsed), selector)
+	if frame.Error != "" {
+		fmt.Print(colorRed, " error: ", frame.Error, colorReset)
+		if frame.RevertReason != "" {
+			fmt.Print(colorRed, " (", frame.RevertReason, ")", colorReset)
+		} else if len(frame.Output) > 0 {
+			fmt.Print(colorRed, " (output ", hexutil.Encode(frame.Output), ")", colorReset)
+		}
+	}
+	fmt.Println()
+
+	for i := range frame.Calls {
+		printCallFrame(&frame.Calls[i], depth+1)
+	}
+}
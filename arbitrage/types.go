@@ -3,9 +3,13 @@ package arbitrage
 import (
 	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
+	"io"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/0xtrooper/flashbots_client"
@@ -19,29 +23,266 @@ const (
 	ParaswapProtocol Protocol = "paraswap"
 )
 
+type TxType string
+
+const (
+	TxType1559   TxType = "1559"
+	TxTypeLegacy TxType = "legacy"
+)
+
 type DataIn struct {
 	Command                         string
 	LocalReth                       bool
 	MinipoolAddresses               []common.Address
+	ExcludeMinipools                []common.Address // never distributed, even if otherwise eligible
 	NodeAddressPrivateKey           *ecdsa.PrivateKey
 	NodeAddress                     *common.Address
+	FeePayerPrivateKey              *ecdsa.PrivateKey // signs the arbitrage tx instead of the node, nil uses the node
+	FeePayerAddress                 *common.Address
+	DropFailing                     bool // drop minipools whose distribute reverts in the simulation and rebuild the bundle
+	ShowApr                         bool // print the profit relative to the ETH bonded by the node
+	SeparateSigners                 bool // require the fee payer to sign the arbitrage tx, the node only signs the distribute txs
 	ReceiverAddress                 *common.Address
 	Client                          *ethclient.Client
 	FbClient                        *flashbots_client.FlashbotsClient
 	RandomPrivateKey                bool
 	SkipConfirmation                bool
 	CheckProfit                     bool
+	AutoThreshold                   float64 // margin over the expected fees at the current base fee, 0 if disabled
 	CheckProfitIgnoreDistributeCost bool
+	CheckProfitBoth                 bool // require the profit to cover the arbitrage fees and the bundle fees, reporting each failure
 	DryRun                          bool
 	Ratelimit                       int
-	Protocol                        Protocol
-	NetworkId                       uint64
+	MaxPriceImpactPct               float64
+	DumpBundle                      string // format of the bundle dump, only "json" is supported
+	DumpBundleFile                  string
+	EmitSendBundle                  string            // file for the eth_sendBundle requests of the bundle, "-" for stdout, empty disables
+	EmitScript                      string            // file for the resolved parameters of the run, empty disables
+	Flags                           map[string]string // flags as given on the command line, for EmitScript
+	ConfirmAmountAbove              float64           // require typing the ETH amount for confirmation above this, 0 disables
+	OnSuccessCmd                    string
+	OnFailureCmd                    string
+	Trace                           bool
+	SimulationDetails               bool          // print the per-tx simulation results, also on success
+	GasBudget                       uint64        // max gas of all bundle txs combined, 0 disables
+	BundleSize                      int           // max minipools per bundle, 0 sends a single bundle
+	MaxBundles                      int           // max bundles per invocation when splitting, 0 is unlimited
+	StateFile                       string        // records the outcome per minipool, empty disables
+	Resume                          bool          // skip minipools marked as distributed in StateFile
+	JSONOutput                      string        // file for the machine-readable run report, empty disables
+	MaxEthSpend                     *big.Int      // max ETH leaving the node wallet in all bundles in wei, value plus max fees, nil disables
+	MaxTotalValue                   *big.Int      // max distributed value plus fees of all bundles in wei, nil disables
+	DedupeWindow                    time.Duration // refuse resubmitting the same minipool set within this window, 0 disables
+	SubmissionLog                   string
+	Precision                       int             // decimals of the ETH amounts in the summary
+	Top                             int             // only build the best minipools by estimated net profit per gas, 0 disables
+	Nonce                           *uint64         // nonce of the first bundle tx, nil uses the pending nonce
+	NonceOffset                     uint64          // added to the pending nonce, e.g. to skip txs sent with other tooling
+	MaxPoolSharePct                 float64         // max share of the pool's rETH the uniswap swap may buy, 0 disables
+	FeeRefundPct                    float64         // assumed share of the priority fees refunded by the relay, summary only
+	DonatePct                       float64         // share of the net profit sent to DonateAddress in the bundle, 0 disables
+	DonateAddress                   *common.Address // recipient of the donation
+	MinMinipools                    int             // refuse runs with fewer minipools, 0 disables
+	Force                           bool            // override MinMinipools and MaxPlausibleProfit
+	MinAbsoluteProfit               *big.Int        // minimum net profit in wei of the profit check, nil disables
+	MaxPlausibleProfit              *big.Int        // expected profit in wei above which a modeling error is assumed, nil disables
+	ChainlinkFeed                   *common.Address // ETH/USD feed for USD values in the summary, nil disables
+	LogFile                         string          // appends the simulated and actual gas of every submitted bundle, empty disables
+	GasBufferPct                    int             // added to the estimated gas of each distribute call
+	Ledger                          string          // CSV file an included arbitrage bundle appends its realized, net and cumulative profit to, empty disables
+	DistributePriority              bool            // allow the arbitrage tx to revert, so it never blocks the distribute txs
+	Confirmations                   uint64          // blocks to wait after the inclusion before reporting success, 0 disables
+	ConfirmationTimeout             time.Duration   // max wait for the confirmations
+	InclusionConfirmations          uint64          // blocks the tx must be deep on chain to count as included instead of trusting the relay wait, 0 disables
+	MaxRuntime                      time.Duration   // wall-clock limit of the whole invocation, 0 if disabled
+	StateOverride                   StateOverride   // if set, the bundle is simulated on the rpc with these overrides
+	Label                           string          // campaign label attached to logs, reports, traces and hooks
+	ExplorerUrl                     string          // block explorer base url for tx links, empty for the network default
+	ProfitOutput                    io.Writer       // if set, only the expected profit is written to it and the bundle is not sent
+	Deadline                        time.Time       // start plus MaxRuntime, zero if disabled
+	ValidBlocks                     uint64          // number of blocks the bundle is submitted for, 0 uses DEFAULT_VALID_BLOCKS
+	OtelEndpoint                    string          // OTLP/HTTP collector for a trace of the run, empty disables
+	Strict                          bool            // abort instead of warning if the node address has pending txs
+	SimulationOffset                uint64          // blocks after the current block the bundle is simulated in, 0 simulates in the current block
+	NoBroadcast                     bool            // only submit to the default relay of FbClient instead of all builders
+	BeaconUrl                       string          // beacon node to cross-check the minipool balances with, empty disables
+	MinMinipoolAge                  time.Duration   // only minipools whose validator exited at least this long ago, needs BeaconUrl, 0 disables
+	MaxMinipoolAge                  time.Duration   // only minipools whose validator exited at most this long ago, needs BeaconUrl, 0 disables
+	FeeLadder                       []*big.Int      // tips in wei, one resubmission per step until included
+	MaxPriorityFee                  *big.Int        // caps the tip of all txs, also the fee ladder steps, nil disables
+	FeeCap                          *big.Int        // fixed fee cap of all txs in wei instead of the boosted suggested gas price, nil disables
+	TipCap                          *big.Int        // fixed tip of all txs in wei instead of the suggested tip, nil disables
+	Scientific                      bool            // print amounts below the precision in scientific notation
+	VerifyBytecode                  bool            // compare the arbitrage contract code hash and check the minipools are registered in Rocket Pool
+	ArbitrageCodeHash               *common.Hash    // expected code hash of the arbitrage contract, nil uses the hash of the compiled contract
+	Timings                         bool            // print and report the duration of each phase
+	Advisory                        bool            // build and simulate, report the opportunity and never prompt or send
+	CommissionTiers                 CommissionTiers // min estimated net profit per minipool by commission, empty disables
+
+	session        *session // shared by all bundles of one ExecuteDistribute call
+	tipOverride    *big.Int // tip of the current fee ladder step
+	nonceRefreshes int      // rebuilds of the current bundle after its nonces went stale
+	Protocol       Protocol
+	TxType         TxType
+	NetworkId      uint64
+}
+
+var ErrMaxTotalValueReached = errors.New("max total value reached")
+
+var ErrMaxEthSpendReached = errors.New("max ETH spend reached")
+
+var ErrTooFewMinipools = errors.New("too few minipools")
+
+// ErrNothingToDistribute is returned if no minipool is left to distribute, it ends the run without failure
+var ErrNothingToDistribute = errors.New("no distributable minipools found")
+
+// ErrInclusionUnknown is returned if the bundle was submitted, but it could not be told in time whether it landed
+var ErrInclusionUnknown = errors.New("bundle inclusion unknown")
+
+var ErrPendingTransactions = errors.New("node address has pending transactions")
+
+// ErrBytecodeMismatch is returned by the bytecode audit if a contract is not the expected one
+var ErrBytecodeMismatch = errors.New("contract bytecode does not match")
+
+// ErrProfitRecipientMismatch is returned if the receiver does not get the expected profit in the simulation
+var ErrProfitRecipientMismatch = errors.New("profit does not reach the receiver")
+
+// ErrImplausibleProfit is returned if the expected profit is above MaxPlausibleProfit and Force is not set
+var ErrImplausibleProfit = errors.New("expected profit is implausibly large")
+
+// ErrRunNotSucceeded is returned if a run ended without error, but not all bundles were included
+var ErrRunNotSucceeded = errors.New("not all bundles were included")
+
+// session tracks totals across the bundles of a single ExecuteDistribute call
+type session struct {
+	valueAtRisk *big.Int // distributed value plus max fees of all included bundles
+	ethSpent    *big.Int // value plus max fees of the node's txs in all included bundles
+}
+
+// ExchangeRateSource describes where the protocol rETH exchange rate used for the burn amount came from
+type ExchangeRateSource struct {
+	Contract common.Address
+	Method   string   // method used to convert the rETH share into the rETH to burn
+	Block    uint64   // block the rate was read at
+	Rate     *big.Int // ETH per rETH with 18 decimals, from getExchangeRate()
+}
+
+// MinipoolShare holds how a minipool balance is split on distribution
+type MinipoolShare struct {
+	Address   common.Address
+	Balance   *big.Int
+	RethShare *big.Int // ETH sent to the rETH contract
+	NodeShare *big.Int
+}
+
+// RunReport collects the outcome of a single ExecuteDistribute run
+type RunReport struct {
+	MinipoolCount  int
+	Minipools      []common.Address
+	ExpectedProfit *big.Int // nil for local rETH or if the build failed
+	RethShare      *big.Int
+	RethToBurn     *big.Int
+	ExchangeRate   *ExchangeRateSource
+	PoolPrice      *PoolPriceChange // nil for local rETH, if the build failed or the pool price could not be read
+	BundleHash     common.Hash
+	TxHash         common.Hash   // arbitrage or burn tx
+	Txs            []IncludedTx  // all txs of the bundle, only set once it is included
+	Simulation     []SimulatedTx // per-tx results of the last simulation
+	Timings        []PhaseTiming // duration of each phase, only collected with DataIn.Timings
+	DryRun         bool
+	Included       bool
+	Builder        *BuilderInfo // nil if not included or unknown
+	ConfirmedBlock uint64       // block of the tx after the requested confirmations, 0 if not waited for
+	Inclusion      string       // landed, not-landed or unknown as seen on chain, empty without DataIn.InclusionConfirmations
+	InclusionBlock uint64       // block the tx landed in, 0 if it did not land or the inclusion was not checked
+	// not included, but all minipools were distributed by someone else in the meantime
+	DistributedElsewhere bool
+	NothingToDistribute  bool             // no minipool was left to distribute, nothing was built
+	Dropped              []common.Address // minipools left out because their distribute reverted in the simulation
+	Err                  error
+}
+
+// IncludedTx is a tx of an included bundle
+type IncludedTx struct {
+	Type string // Distribute, Arbitrage, Burn or Donation
+	Hash common.Hash
+}
+
+// SimulatedTx is the simulation result of a bundle tx, the coinbase diff is its payment to the builder
+type SimulatedTx struct {
+	Hash              common.Hash
+	To                common.Address
+	GasUsed           uint64
+	CoinbaseDiff      *big.Int // gas fees plus ETH sent to the coinbase, nil if not reported
+	EthSentToCoinbase *big.Int
+	GasFees           *big.Int
+	Reverted          bool
+}
+
+// simulatedTxs converts the per-tx results of a simulation for the report
+func simulatedTxs(res *flashbots_client.SimulationResultBundle) []SimulatedTx {
+	if res == nil {
+		return nil
+	}
+
+	txs := make([]SimulatedTx, len(res.Results))
+	for i, tx := range res.Results {
+		txs[i] = SimulatedTx{
+			Hash:              tx.TxHash,
+			To:                tx.ToAddress,
+			GasUsed:           tx.GasUsed,
+			CoinbaseDiff:      tx.CoinbaseDiff,
+			EthSentToCoinbase: tx.EthSentToCoinbase,
+			GasFees:           tx.GasFees,
+			Reverted:          tx.Error != "",
+		}
+	}
+	return txs
+}
+
+// PoolPriceChange is the expected effect of the arbitrage swap on the uniswap pool
+type PoolPriceChange struct {
+	Before         float64 // WETH per rETH
+	After          float64
+	ImpactPct      float64
+	DiscountBefore float64 // pool price below the protocol rate in percent
+	DiscountAfter  float64
+}
+
+// Succeeded reports whether the bundle was included, the minipools were distributed elsewhere, the dry run completed
+// or there was nothing to distribute
+func (r *RunReport) Succeeded() bool {
+	return r.Err == nil && (r.Included || r.DistributedElsewhere || r.DryRun || r.NothingToDistribute)
+}
+
+// BuildResult holds a built bundle together with the amounts it was calculated from
+type BuildResult struct {
+	Bundle         *flashbots_client.Bundle
+	ArbitrageTx    *types.Transaction // arbitrage or burn tx, referenced by identity as other txs may follow it
+	DonationTx     *types.Transaction // nil without a donation
+	ExpectedProfit *big.Int           // arbitrage profit before gas fees and after the donation, nil for local rETH
+	RethShare      *big.Int           // ETH sent to the rETH contract by the distribute calls
+	RethToBurn     *big.Int           // rETH burned at the protocol rate
+
+	ExchangeRate *ExchangeRateSource
+	UsdPrice     *ChainlinkPrice // nil if no feed is configured or it could not be read
+
+	IncludedMinipools []common.Address
+	DeferredMinipools []common.Address // left out because of the gas budget or --top
+
+	PoolPriceBefore float64 // uniswap pool price in WETH per rETH before the arbitrage swap, 0 if it could not be read
+	PoolPriceAfter  float64 // uniswap pool price in WETH per rETH after the arbitrage swap
+	PriceImpactPct  float64
+
+	PoolDiscountBeforePct float64 // pool price below the protocol rate in percent, negative for a premium
+	PoolDiscountAfterPct  float64
 }
 
 type UniswapArbitrage struct {
 	expectedProfit          *big.Int
 	expectedProfitAfterFees *big.Int
 
+	rethShare         *big.Int
 	swapInAmountWeth  *big.Int
 	swapOutAmountReth *big.Int
 	sqrtPriceLimitX96 *big.Int
@@ -61,6 +302,7 @@ func (ua *UniswapArbitrage) GetSwapInAmountWeth() *big.Int {
 type ParaswapArbitrage struct {
 	expectedProfit          *big.Int
 	expectedProfitAfterFees *big.Int
+	rethShare               *big.Int
 	swapInAmountWeth        *big.Int
 	swapOutAmountReth       *big.Int
 	expectedFee             int
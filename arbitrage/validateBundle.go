@@ -0,0 +1,163 @@
+package arbitrage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/0xtrooper/flashbots_client"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// validateBundle checks the bundle the way the relay and builders would see it, without sending it.
+// First the transactions are checked locally (chain id, signer, nonces, fees), then the bundle is
+// simulated again with the target block set, exactly as it would be submitted.
+func validateBundle(ctx context.Context, logger *slog.Logger, dataIn *DataIn, bundle *flashbots_client.Bundle) error {
+	txs := bundle.Transactions()
+	if len(txs) == 0 {
+		return errors.New("bundle has no transactions")
+	}
+
+	nonce, err := getStartNonce(ctx, *dataIn)
+	if err != nil {
+		return err
+	}
+
+	// next expected nonce per allowed sender
+	nonces := map[common.Address]uint64{*dataIn.NodeAddress: nonce}
+	if dataIn.FeePayerAddress != nil {
+		nonces[*dataIn.FeePayerAddress], err = getCurrentNonce(ctx, dataIn.Client, *dataIn.FeePayerAddress, dataIn.Ratelimit)
+		if err != nil {
+			return errors.Join(errors.New("failed to get fee payer nonce"), err)
+		}
+	}
+
+	chainId := new(big.Int).SetUint64(dataIn.NetworkId)
+	signer := types.LatestSignerForChainID(chainId)
+
+	var errs []error
+	for i, tx := range txs {
+		if tx.ChainId().Cmp(chainId) != 0 {
+			errs = append(errs, fmt.Errorf("tx %d: chain id %s does not match network %d", i+1, tx.ChainId(), dataIn.NetworkId))
+		}
+
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			errs = append(errs, errors.Join(fmt.Errorf("tx %d: invalid signature", i+1), err))
+		} else if expected, ok := nonces[sender]; !ok {
+			errs = append(errs, fmt.Errorf("tx %d: signed by %s instead of the node address %s", i+1, sender.Hex(), dataIn.NodeAddress.Hex()))
+		} else {
+			if tx.Nonce() != expected {
+				errs = append(errs, fmt.Errorf("tx %d: nonce %d, expected %d", i+1, tx.Nonce(), expected))
+			}
+			nonces[sender] = expected + 1
+		}
+
+		if tx.To() == nil {
+			errs = append(errs, fmt.Errorf("tx %d: missing recipient", i+1))
+		}
+
+		if tx.GasTipCap().Cmp(tx.GasFeeCap()) > 0 {
+			errs = append(errs, fmt.Errorf("tx %d: priority fee is higher than the max fee", i+1))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	logger.Debug("bundle passed local validation", slog.Int("txs", len(txs)))
+
+	// the relay simulates on the real state, the bundle was built for the overridden one
+	if dataIn.StateOverride != nil {
+		logger.Debug("skipping relay validation with state overrides")
+		return nil
+	}
+
+	blockNumber, err := dataIn.Client.BlockNumber(ctx)
+	if err != nil {
+		return errors.Join(errors.New("failed to get block number"), err)
+	}
+
+	// simulate a copy, the original bundle stays untouched for a real run
+	submission := bundle.Copy()
+	if !dataIn.NoBroadcast {
+		submission.UseAllBuilders(dataIn.NetworkId)
+	}
+	err = submission.SetTargetBlockNumber(blockNumber + 1)
+	if err != nil {
+		return errors.Join(errors.New("failed to set target block"), err)
+	}
+
+	res, success, err := dataIn.FbClient.SimulateBundle(submission, blockNumber)
+	if err != nil {
+		return errors.Join(errors.New("relay rejected the bundle"), err)
+	}
+	if !success {
+		for i, tx := range res.Results {
+			if tx.Error != "" {
+				return fmt.Errorf("tx %d reverted in the relay simulation for block %d: %s", i+1, blockNumber+1, tx.Error)
+			}
+		}
+		return fmt.Errorf("relay simulation for block %d failed", blockNumber+1)
+	}
+
+	return nil
+}
+
+// bundleSnapshot holds the encoded txs of a bundle at the time it was simulated
+type bundleSnapshot [][]byte
+
+func snapshotBundle(bundle *flashbots_client.Bundle) (bundleSnapshot, error) {
+	txs := bundle.Transactions()
+	snapshot := make(bundleSnapshot, 0, len(txs))
+	for i, tx := range txs {
+		encoded, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to encode tx %d", i+1), err)
+		}
+		snapshot = append(snapshot, encoded)
+	}
+
+	return snapshot, nil
+}
+
+// verify errors if the txs of the bundle are not byte-for-byte the simulated ones, in the same order
+func (s bundleSnapshot) verify(bundle *flashbots_client.Bundle) error {
+	current, err := snapshotBundle(bundle)
+	if err != nil {
+		return err
+	}
+
+	if len(current) != len(s) {
+		return fmt.Errorf("bundle has %d txs, %d were simulated", len(current), len(s))
+	}
+	for i := range s {
+		if !bytes.Equal(current[i], s[i]) {
+			return fmt.Errorf("tx %d differs from the simulated one", i+1)
+		}
+	}
+
+	return nil
+}
+
+// verifySubmission checks the bundles sent for each of the next validBlocks blocks still hold the simulated txs
+func verifySubmission(simulated bundleSnapshot, bundle *flashbots_client.Bundle, validBlocks uint64) error {
+	submissions, err := bundle.GetBundelsForNextNBlocks(validBlocks)
+	if err != nil {
+		return errors.Join(errors.New("failed to get the bundles to submit"), err)
+	}
+
+	for _, submission := range append([]*flashbots_client.Bundle{bundle}, submissions...) {
+		err = simulated.verify(submission)
+		if err != nil {
+			return fmt.Errorf("bundle for block %d was modified after the simulation: %w", submission.TargetBlockNumber(), err)
+		}
+	}
+
+	return nil
+}
@@ -1,24 +1,119 @@
 package arbitrage
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
+	"rocketpoolArbitrage/arbitrage/contract"
 	"rocketpoolArbitrage/rocketpoolContracts/minipoolDelegate"
+	"strings"
 	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// ParseAddress validates an address input and returns it normalized. name describes the input in errors,
+// e.g. "minipool". Surrounding spaces and quotes are removed. Input that is not checksummed or has a wrong
+// checksum is accepted with a warning, since it still describes a valid address.
+func ParseAddress(logger *slog.Logger, name, value string) (common.Address, error) {
+	value = strings.Trim(value, " \"'")
+
+	if value == "" {
+		return common.Address{}, fmt.Errorf("%s address is empty", name)
+	}
+	if !strings.HasPrefix(value, "0x") && !strings.HasPrefix(value, "0X") {
+		return common.Address{}, fmt.Errorf("%s address _%s_ is invalid: missing 0x prefix", name, value)
+	}
+	if len(value) != 2+2*common.AddressLength {
+		return common.Address{}, fmt.Errorf("%s address _%s_ is invalid: expected %d hex characters, got %d", name, value, 2*common.AddressLength, len(value)-2)
+	}
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("%s address _%s_ is invalid: not a hex string", name, value)
+	}
+
+	address := common.HexToAddress(value)
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%s address must not be the zero address", name)
+	}
+
+	hexPart := value[2:]
+	switch {
+	case hexPart == strings.ToLower(hexPart) || hexPart == strings.ToUpper(hexPart):
+		logger.Warn("address is not checksummed", slog.String("input", name), slog.String("address", address.Hex()))
+	case "0x"+hexPart != address.Hex():
+		logger.Warn("address checksum does not match, check for typos", slog.String("input", name), slog.String("given", value), slog.String("checksummed", address.Hex()))
+	}
+
+	return address, nil
+}
+
 func VerifyInputData(ctx context.Context, logger *slog.Logger, dataIn *DataIn) error {
 	logger.With(slog.String("function", "VerifyInputData"))
 
+	// a legacy tx has no tip of its own
+	if dataIn.TxType == TxTypeLegacy && (len(dataIn.FeeLadder) > 0 || dataIn.MaxPriorityFee != nil || dataIn.TipCap != nil) {
+		return errors.New("\"--fee-ladder\", \"--max-priority-fee\" and \"--tip-cap\" require \"--tx-type=1559\"")
+	}
+
+	// the minipool list is always given explicitly, an excluded minipool in it is a conflict
+	for _, excluded := range dataIn.ExcludeMinipools {
+		for _, minipoolAddress := range dataIn.MinipoolAddresses {
+			if excluded == minipoolAddress {
+				return fmt.Errorf("%s: minipool is both listed and excluded", minipoolAddress)
+			}
+		}
+	}
+
+	// addresses resolved from the smartnode or private keys did not go through ParseAddress
+	for _, minipoolAddress := range dataIn.MinipoolAddresses {
+		if minipoolAddress == (common.Address{}) {
+			return errors.New("minipool address must not be the zero address")
+		}
+	}
+	if dataIn.NodeAddress != nil && *dataIn.NodeAddress == (common.Address{}) {
+		return errors.New("node address must not be the zero address")
+	}
+	if dataIn.ReceiverAddress != nil && *dataIn.ReceiverAddress == (common.Address{}) {
+		return errors.New("receiver address must not be the zero address")
+	}
+
 	var verifyAllCallsFromNO bool
 	if dataIn.NodeAddress == nil {
 		verifyAllCallsFromNO = true
 	}
 
+	// the arbitrage contract is not used when burning local rETH
+	if !dataIn.LocalReth {
+		err := verifyArbitrageContract(ctx, logger, dataIn)
+		if err != nil {
+			return err
+		}
+	}
+
+	var minipoolAudit *minipoolRegistry
+	if dataIn.VerifyBytecode {
+		registry, err := newMinipoolRegistry(ctx, dataIn)
+		if err != nil {
+			return err
+		}
+		minipoolAudit = registry
+	}
+
 	for _, minipoolAddress := range dataIn.MinipoolAddresses {
+		// before any call to the minipool, an unregistered contract could answer anything
+		if minipoolAudit != nil {
+			err := minipoolAudit.verify(ctx, logger, dataIn, minipoolAddress)
+			if err != nil {
+				return err
+			}
+		}
+
 		minipoolInstance, err := minipoolDelegate.NewMinipoolDelegate(minipoolAddress, dataIn.Client)
 		if err != nil {
 			return errors.Join(fmt.Errorf("%s: failed to create minipool instance", minipoolAddress), err)
@@ -96,5 +191,280 @@ func VerifyInputData(ctx context.Context, logger *slog.Logger, dataIn *DataIn) e
 		}
 	}
 
+	// the node address is known from the minipools at this point
+	if dataIn.Nonce != nil {
+		verifyNonce(ctx, logger, dataIn)
+	}
+
+	if dataIn.FeePayerAddress != nil && dataIn.NodeAddress != nil && *dataIn.FeePayerAddress == *dataIn.NodeAddress {
+		return errors.New("the fee payer is the node address, its txs would use the same nonces")
+	}
+	if dataIn.SeparateSigners && dataIn.FeePayerAddress == nil {
+		return errors.New("separate signers require a fee payer to sign the arbitrage tx")
+	}
+
+	err := checkPendingTransactions(ctx, logger, dataIn, "node address", dataIn.NodeAddress)
+	if err != nil {
+		return err
+	}
+
+	if dataIn.FeePayerAddress != nil {
+		err = checkPendingTransactions(ctx, logger, dataIn, "fee payer", dataIn.FeePayerAddress)
+		if err != nil {
+			return err
+		}
+
+		maxGas := int64(ARBITRAGE_PARASWAP_CALL_MAX_GAS)
+		if dataIn.Protocol == UniswapProtocol {
+			maxGas = ARBITRAGE_UNISWAP_CALL_MAX_GAS
+		}
+		err = verifySignerBalance(ctx, logger, dataIn, "fee payer", *dataIn.FeePayerAddress, "the arbitrage tx", maxGas)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the node only pays for the distribute txs, the fee payer for the arbitrage
+	if dataIn.SeparateSigners && dataIn.NodeAddress != nil {
+		// the build reports failed or unusual estimates, here only the limits are needed
+		estimates, _, err := estimateDistributeCalls(ctx, logger, *dataIn, dataIn.MinipoolAddresses)
+		if err != nil {
+			return errors.Join(errors.New("failed to estimate distribute gas"), err)
+		}
+		maxGas := int64(totalGas(distributeGasLimits(*dataIn, dataIn.MinipoolAddresses, estimates), dataIn.MinipoolAddresses))
+		err = verifySignerBalance(ctx, logger, dataIn, "node address", *dataIn.NodeAddress, "the distribute txs", maxGas)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyArbitrageContract checks the arbitrage contract is deployed and, if it exposes a paused() getter, not paused.
+// The current contract has no pause mechanism, so a failing or empty paused() call is not treated as an error.
+func verifyArbitrageContract(ctx context.Context, logger *slog.Logger, dataIn *DataIn) error {
+	arbitrageContractAddress, err := GetArbitrageContractAddress(dataIn.NetworkId)
+	if err != nil {
+		return errors.Join(errors.New("failed to get arbitrage contract address"), err)
+	}
+
+	code, err := dataIn.Client.CodeAt(ctx, arbitrageContractAddress, nil)
+	if err != nil {
+		return errors.Join(errors.New("failed to get arbitrage contract code"), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	if len(code) == 0 {
+		return fmt.Errorf("arbitrage contract %s is not deployed on this network", arbitrageContractAddress.Hex())
+	}
+
+	if dataIn.VerifyBytecode {
+		err = verifyArbitrageCodeHash(logger, dataIn, arbitrageContractAddress, code)
+		if err != nil {
+			return err
+		}
+	}
+
+	output, err := dataIn.Client.CallContract(ctx, ethereum.CallMsg{
+		To:   &arbitrageContractAddress,
+		Data: crypto.Keccak256([]byte("paused()"))[:4],
+	}, nil)
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+	if err != nil || len(output) != 32 {
+		logger.Debug("arbitrage contract does not expose paused()", slog.String("contract", arbitrageContractAddress.Hex()))
+		return nil
+	}
+
+	if new(big.Int).SetBytes(output).Sign() != 0 {
+		return fmt.Errorf("arbitrage contract %s is paused, try again later", arbitrageContractAddress.Hex())
+	}
+
+	logger.Debug("arbitrage contract is not paused", slog.String("contract", arbitrageContractAddress.Hex()))
+	return nil
+}
+
+// verifyArbitrageCodeHash compares the hash of the deployed arbitrage contract code against ArbitrageCodeHash or,
+// if not set, against the runtime code of the compiled contract shipped with the tool
+func verifyArbitrageCodeHash(logger *slog.Logger, dataIn *DataIn, arbitrageContractAddress common.Address, code []byte) error {
+	expected := dataIn.ArbitrageCodeHash
+	if expected == nil {
+		runtime, err := runtimeCode(common.FromHex(contract.ContractMetaData.Bin))
+		if err != nil {
+			return errors.Join(errors.New("failed to get the runtime code of the compiled arbitrage contract"), err)
+		}
+		hash := crypto.Keccak256Hash(runtime)
+		expected = &hash
+	}
+
+	actual := crypto.Keccak256Hash(code)
+	if actual != *expected {
+		return fmt.Errorf("%w: arbitrage contract %s has code hash %s, expected %s", ErrBytecodeMismatch, arbitrageContractAddress.Hex(), actual.Hex(), expected.Hex())
+	}
+
+	logger.Info("verified arbitrage contract bytecode", slog.String("contract", arbitrageContractAddress.Hex()), slog.String("codeHash", actual.Hex()))
+	return nil
+}
+
+// runtimeCode extracts the deployed code from creation code ending in the usual solc constructor epilogue
+// PUSH2 length DUP1 PUSH2 offset PUSH0 CODECOPY PUSH0 RETURN. Constructor arguments and immutables are not supported.
+func runtimeCode(creationCode []byte) ([]byte, error) {
+	epilogue := bytes.Index(creationCode, []byte{0x5f, 0x39, 0x5f, 0xf3})
+	if epilogue < 7 || creationCode[epilogue-7] != 0x61 || creationCode[epilogue-4] != 0x80 || creationCode[epilogue-3] != 0x61 {
+		return nil, errors.New("unexpected creation code")
+	}
+
+	length := int(creationCode[epilogue-6])<<8 | int(creationCode[epilogue-5])
+	offset := int(creationCode[epilogue-2])<<8 | int(creationCode[epilogue-1])
+	if offset+length > len(creationCode) {
+		return nil, errors.New("runtime code exceeds the creation code")
+	}
+
+	return creationCode[offset : offset+length], nil
+}
+
+// minipoolRegistry checks minipools against the RocketMinipoolManager, only contracts created by Rocket Pool are registered
+type minipoolRegistry struct {
+	managerABI     abi.ABI
+	managerAddress common.Address
+}
+
+func newMinipoolRegistry(ctx context.Context, dataIn *DataIn) (*minipoolRegistry, error) {
+	managerAddress, err := getRocketpoolContractAddress(ctx, dataIn.Client, dataIn.NetworkId, "rocketMinipoolManager", dataIn.Ratelimit)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool manager address"), err)
+	}
+
+	managerABI, err := abi.JSON(strings.NewReader(MinipoolManagerABI))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get minipool manager ABI"), err)
+	}
+
+	return &minipoolRegistry{managerABI: managerABI, managerAddress: managerAddress}, nil
+}
+
+func (r *minipoolRegistry) verify(ctx context.Context, logger *slog.Logger, dataIn *DataIn, minipoolAddress common.Address) error {
+	values, err := callViewFunction(ctx, dataIn.Client, r.managerABI, r.managerAddress, dataIn.Ratelimit, "getMinipoolExists", minipoolAddress)
+	if err != nil {
+		return errors.Join(fmt.Errorf("%s: failed to check the minipool is registered", minipoolAddress.Hex()), err)
+	}
+
+	exists, ok := values[0].(bool)
+	if !ok {
+		return errors.New("unexpected getMinipoolExists output")
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s is not a minipool registered in Rocket Pool", ErrBytecodeMismatch, minipoolAddress.Hex())
+	}
+
+	code, err := dataIn.Client.CodeAt(ctx, minipoolAddress, nil)
+	if err != nil {
+		return errors.Join(fmt.Errorf("%s: failed to get minipool code", minipoolAddress.Hex()), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	logger.Info("verified minipool", slog.String("minipool", minipoolAddress.Hex()), slog.String("codeHash", crypto.Keccak256Hash(code).Hex()))
+	return nil
+}
+
+// verifyNonce warns if the explicitly set nonce is already used on chain, such a bundle can never be included.
+// Only the confirmed nonce is compared, a nonce above it may be intended to skip txs pending elsewhere.
+func verifyNonce(ctx context.Context, logger *slog.Logger, dataIn *DataIn) {
+	if dataIn.NodeAddress == nil {
+		return
+	}
+
+	chainNonce, err := dataIn.Client.NonceAt(ctx, *dataIn.NodeAddress, nil)
+	if err != nil {
+		logger.Warn("failed to get nonce of the node address", slog.String("error", err.Error()))
+		return
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	if *dataIn.Nonce < chainNonce {
+		logger.Warn("supplied nonce is lower than the current nonce of the node address, the bundle cannot be included",
+			slog.Uint64("nonce", *dataIn.Nonce),
+			slog.Uint64("currentNonce", chainNonce),
+		)
+	}
+}
+
+// checkPendingTransactions warns, or fails with Strict, if the signer has txs in the mempool. The bundle
+// is built on the pending nonce, it can only be included once these txs are mined, and a pending tx using the
+// same nonce would replace the bundle's tx.
+func checkPendingTransactions(ctx context.Context, logger *slog.Logger, dataIn *DataIn, name string, address *common.Address) error {
+	if address == nil {
+		return nil
+	}
+
+	confirmedNonce, err := dataIn.Client.NonceAt(ctx, *address, nil)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to get nonce of the %s", name), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	pendingNonce, err := dataIn.Client.PendingNonceAt(ctx, *address)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to get pending nonce of the %s", name), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	if pendingNonce <= confirmedNonce {
+		return nil
+	}
+
+	pending := pendingNonce - confirmedNonce
+	if dataIn.Strict {
+		return fmt.Errorf("%w: %d pending txs from %s %s, wait until they are mined", ErrPendingTransactions, pending, name, address.Hex())
+	}
+
+	logger.Warn(name+" has pending transactions, the bundle can only be included once they are mined",
+		slog.String("address", address.Hex()),
+		slog.Uint64("pending", pending),
+		slog.Uint64("confirmedNonce", confirmedNonce),
+		slog.Uint64("pendingNonce", pendingNonce),
+	)
+	return nil
+}
+
+// verifySignerBalance checks that the signer can pay the max fee of maxGas at the current gas price.
+// For the arbitrage tx the max gas of the paraswap call is assumed unless only uniswap is used.
+func verifySignerBalance(ctx context.Context, logger *slog.Logger, dataIn *DataIn, name string, address common.Address, txs string, maxGas int64) error {
+	baseGas, _, err := getCurrentGasSettings(ctx, dataIn.Client, dataIn.Ratelimit)
+	if err != nil {
+		return errors.Join(errors.New("failed to get current gas settings"), err)
+	}
+	baseGasBoosted := new(big.Int).Div(new(big.Int).Mul(baseGas, big.NewInt(150)), big.NewInt(100))
+	if dataIn.FeeCap != nil {
+		baseGasBoosted = dataIn.FeeCap
+	}
+
+	required := new(big.Int).Mul(baseGasBoosted, big.NewInt(maxGas))
+
+	balance, err := dataIn.Client.BalanceAt(ctx, address, nil)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to get %s balance", name), err)
+	}
+	if dataIn.Ratelimit > 0 {
+		time.Sleep(time.Duration(dataIn.Ratelimit) * time.Millisecond)
+	}
+
+	logger.Debug("signer balance", slog.String("signer", name), slog.String("address", address.Hex()), slog.String("balance", balance.String()), slog.String("required", required.String()))
+	if balance.Cmp(required) < 0 {
+		return fmt.Errorf("%s %s has %.6f ETH, %s may cost up to %.6f ETH", name, address.Hex(), weiToEth(balance), txs, weiToEth(required))
+	}
+
 	return nil
 }
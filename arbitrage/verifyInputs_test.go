@@ -0,0 +1,88 @@
+package arbitrage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math/big"
+	"rocketpoolArbitrage/arbitrage/contract"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func Test_ParseAddress(t *testing.T) {
+	rETH := common.HexToAddress("0xae78736Cd615f374D3085123A210448E74Fc6393")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    common.Address
+		wantErr bool
+	}{
+		{"checksummed", "0xae78736Cd615f374D3085123A210448E74Fc6393", rETH, false},
+		{"lowercase", "0xae78736cd615f374d3085123a210448e74fc6393", rETH, false},
+		{"wrong checksum", "0xAe78736Cd615f374D3085123A210448E74Fc6393", rETH, false},
+		{"quoted", "\"0xae78736Cd615f374D3085123A210448E74Fc6393\" ", rETH, false},
+		{"empty", "", common.Address{}, true},
+		{"missing prefix", "ae78736Cd615f374D3085123A210448E74Fc6393", common.Address{}, true},
+		{"too short", "0xae78736Cd615f374D3085123A210448E74Fc639", common.Address{}, true},
+		{"not hex", "0xzz78736Cd615f374D3085123A210448E74Fc6393", common.Address{}, true},
+		{"zero", "0x0000000000000000000000000000000000000000", common.Address{}, true},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAddress(logger, "test", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAddress() = %s, want %s", got.Hex(), tt.want.Hex())
+			}
+		})
+	}
+}
+
+func Test_runtimeCode(t *testing.T) {
+	runtime, err := runtimeCode(common.FromHex(contract.ContractMetaData.Bin))
+	if err != nil {
+		t.Fatalf("runtimeCode() error = %v", err)
+	}
+	if len(runtime) != 0x154d {
+		t.Errorf("runtimeCode() length = %d, want %d", len(runtime), 0x154d)
+	}
+	if !bytes.HasPrefix(runtime, []byte{0x60, 0x80, 0x60, 0x40, 0x52}) {
+		t.Errorf("runtimeCode() does not start with the free memory pointer setup")
+	}
+
+	_, err = runtimeCode([]byte{0x60, 0x80, 0x60, 0x40, 0x52})
+	if err == nil {
+		t.Errorf("runtimeCode() of code without constructor epilogue did not fail")
+	}
+}
+
+func Test_VerifyInputDataLegacyFees(t *testing.T) {
+	tip := big.NewInt(1e9)
+
+	tests := []struct {
+		name   string
+		dataIn *DataIn
+	}{
+		{"tip cap", &DataIn{TxType: TxTypeLegacy, TipCap: tip}},
+		{"max priority fee", &DataIn{TxType: TxTypeLegacy, MaxPriorityFee: tip}},
+		{"fee ladder", &DataIn{TxType: TxTypeLegacy, FeeLadder: []*big.Int{tip}}},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// rejected before any rpc call, the missing client is never used
+			if err := VerifyInputData(context.Background(), logger, tt.dataIn); err == nil {
+				t.Fatal("VerifyInputData() accepted a 1559 fee setting for a legacy tx")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"strconv"
 	"time"
@@ -421,3 +422,139 @@ func getBeaconChainBaseUrl(data *Data) (string, error) {
 		return "", errors.New("only mainnet and holesky are supported")
 	}
 }
+
+// GetValidatorBalance returns the status and the current balance in wei of the validator at the head of the beacon chain
+func GetValidatorBalance(eth2Url string, pubkey string) (string, *big.Int, error) {
+	url := fmt.Sprintf("%s/eth/v1/beacon/states/head/validators/%s", eth2Url, pubkey)
+
+	httpClient := &http.Client{
+		Timeout: time.Second * 5,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, errors.Join(errors.New("failed to create request"), err)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", nil, errors.Join(errors.New("failed to send request"), err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", nil, errors.Join(errors.New("failed to read response body"), err)
+	}
+
+	var errResponse struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &errResponse); err == nil && errResponse.Code != 0 {
+		return "", nil, fmt.Errorf("failed to get validator balance %d: %s", errResponse.Code, errResponse.Message)
+	}
+
+	var validatorStatus struct {
+		Data struct {
+			Balance string `json:"balance"`
+			Status  string `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &validatorStatus); err != nil {
+		return "", nil, errors.Join(errors.New("failed to decode response"), err)
+	}
+
+	// the beacon chain reports balances in gwei
+	balanceGwei, ok := new(big.Int).SetString(validatorStatus.Data.Balance, 10)
+	if !ok {
+		return "", nil, fmt.Errorf("failed to convert balance %q", validatorStatus.Data.Balance)
+	}
+
+	return validatorStatus.Data.Status, new(big.Int).Mul(balanceGwei, big.NewInt(1e9)), nil
+}
+
+// GetValidatorExitEpoch returns the exit epoch of the validator at the head of the beacon chain.
+// A validator that has not exited reports FAR_FUTURE_EPOCH (2^64-1).
+func GetValidatorExitEpoch(eth2Url string, pubkey string) (uint64, error) {
+	url := fmt.Sprintf("%s/eth/v1/beacon/states/head/validators/%s", eth2Url, pubkey)
+
+	body, err := getBeaconResponse(url)
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to get validator"), err)
+	}
+
+	var validatorStatus struct {
+		Data struct {
+			Validator struct {
+				ExitEpoch string `json:"exit_epoch"`
+			} `json:"validator"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &validatorStatus); err != nil {
+		return 0, errors.Join(errors.New("failed to decode response"), err)
+	}
+
+	exitEpoch, err := strconv.ParseUint(validatorStatus.Data.Validator.ExitEpoch, 10, 64)
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to convert exit epoch"), err)
+	}
+
+	return exitEpoch, nil
+}
+
+// GetGenesisTime returns the genesis time of the beacon chain, epochs are counted from it
+func GetGenesisTime(eth2Url string) (time.Time, error) {
+	body, err := getBeaconResponse(eth2Url + "/eth/v1/beacon/genesis")
+	if err != nil {
+		return time.Time{}, errors.Join(errors.New("failed to get genesis"), err)
+	}
+
+	var genesis struct {
+		Data struct {
+			GenesisTime string `json:"genesis_time"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &genesis); err != nil {
+		return time.Time{}, errors.Join(errors.New("failed to decode response"), err)
+	}
+
+	seconds, err := strconv.ParseInt(genesis.Data.GenesisTime, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Join(errors.New("failed to convert genesis time"), err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+func getBeaconResponse(url string) ([]byte, error) {
+	httpClient := &http.Client{
+		Timeout: time.Second * 5,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create request"), err)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to send request"), err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read response body"), err)
+	}
+
+	var errResponse struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &errResponse); err == nil && errResponse.Code != 0 {
+		return nil, fmt.Errorf("beacon node returned %d: %s", errResponse.Code, errResponse.Message)
+	}
+
+	return body, nil
+}
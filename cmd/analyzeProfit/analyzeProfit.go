@@ -126,11 +126,11 @@ func parseInput() (common.Address, string, string, error) {
 
 	var nodeAddress common.Address
 	if *nodeAddressFlag != "" {
-		if !common.IsHexAddress(*nodeAddressFlag) {
-			return common.Address{}, "", "", errors.New("invalid node address")
+		var err error
+		nodeAddress, err = arbitrage.ParseAddress(slog.Default(), "node", *nodeAddressFlag)
+		if err != nil {
+			return common.Address{}, "", "", err
 		}
-
-		nodeAddress = common.HexToAddress(*nodeAddressFlag)
 	}
 
 	var eth1Url string
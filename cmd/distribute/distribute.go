@@ -7,13 +7,24 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"math/big"
+	"os"
 	"rocketpoolArbitrage/arbitrage"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/0xtrooper/flashbots_client"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// same exit code as coreutils timeout, so schedulers can tell a timeout from a failed run
+	EXIT_CODE_TIMEOUT = 124
+
+	// time given to the run to return after --max-runtime, e.g. if it is blocked in the confirmation prompt
+	MAX_RUNTIME_GRACE = 10 * time.Second
 )
 
 func main() {
@@ -23,16 +34,53 @@ func main() {
 	logger = logger.With(slog.String("module", "distribute"))
 
 	dataIn, err := parseInput(ctx, logger)
-	if err != nil {
+	if errors.Is(err, arbitrage.ErrNothingToDistribute) {
+		// the common case for scheduled runs between exits, not a failure
 		fmt.Println(err)
 		return
 	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			os.Exit(EXIT_CODE_TIMEOUT)
+		}
+		os.Exit(1)
+	}
+
+	if dataIn.Label != "" {
+		logger = logger.With(slog.String("label", dataIn.Label))
+	}
+
+	if !dataIn.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, dataIn.Deadline)
+		defer cancel()
+
+		go exitAfterDeadline(ctx, dataIn.MaxRuntime)
+	}
 
 	err = arbitrage.ExecuteDistribute(ctx, logger, dataIn)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			fmt.Fprintf(os.Stderr, "max runtime of %s exceeded\n", dataIn.MaxRuntime)
+			os.Exit(EXIT_CODE_TIMEOUT)
+		}
+		os.Exit(1)
+	}
+}
+
+// exitAfterDeadline ends the process if the run does not return in time after the deadline,
+// not every wait can be cancelled through the context
+func exitAfterDeadline(ctx context.Context, maxRuntime time.Duration) {
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
 		return
 	}
+
+	time.Sleep(MAX_RUNTIME_GRACE)
+	fmt.Fprintf(os.Stderr, "max runtime of %s exceeded\n", maxRuntime)
+	os.Exit(EXIT_CODE_TIMEOUT)
 }
 
 func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataIn, err error) {
@@ -41,6 +89,7 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 	data = &arbitrage.DataIn{}
 
 	debugFlag := flag.Bool("debug", false, "Enable detailed debug logs")
+	quietFlag := flag.Bool("quiet", false, "Suppress all output except warnings and errors (on stderr). The exit code reports success or failure. Requires --skip-confirmation, --dry-run or --advisory.")
 	commandFlag := flag.String(
 		"command",
 		"docker exec rocketpool_node /go/bin/rocketpool",
@@ -49,15 +98,18 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 	flag.BoolVar(&data.LocalReth, "local-reth", false, "Use existing local rETH instead of taking a flashloan. If false, the CLI attempts a flashloan")
 	minipoolFlag := flag.String("minipool", "", "Single minipool address to distribute. Use --minipools for multiple.")
 	minipoolsFlag := flag.String("minipools", "", "Comma-separated list of minipool addresses to distribute.")
+	excludeMinipoolsFlag := flag.String("exclude-minipools", "", "Comma-separated list of minipool addresses that must never be distributed.")
 	SercherPrivateKeyFlag := flag.String("searcher-private-key", "", "Private key for the searcher used in Flashbots transactions. If not set, a random key is generated.")
-	rpcFlag := flag.String("rpc", "http://localhost:8545", "Ethereum RPC endpoint for all on-chain calls. (default: http://localhost:8545)")
+	rpcFlag := flag.String("rpc", "http://localhost:8545", "Ethereum RPC endpoint for all on-chain calls. Comma separated http(s) urls fail over to the next on connection errors. (default: http://localhost:8545)")
 	rpcPortFlag := flag.String("rpc-port", "8545", "If using localhost but on a non-default port, override the port here.")
 	flag.BoolVar(&data.SkipConfirmation, "skip-confirmation", false, "Skip confirmation prompt before executing")
 	flag.BoolVar(&data.SkipConfirmation, "y", false, "Short flag for --skip-confirmation")
 	flag.BoolVar(&data.CheckProfit, "check-profit", true, "If enabled, reverts when the profit is too low. (Default: true)")
 	flag.BoolVar(&data.CheckProfitIgnoreDistributeCost, "ignore-distribute-cost", false, "Reverts when the profit is too low, but does not considering the distribute call(s). Best used if you want to distribute either way.")
+	flag.BoolVar(&data.CheckProfitBoth, "check-profit-both", false, "Require the profit to cover the arbitrage fees and the full bundle fees, reporting which condition fails. Cannot be combined with --ignore-distribute-cost.")
 	flag.BoolVar(&data.DryRun, "dry-run", false, "Perform a dry run without sending the bundle to Flashbots; only print the transaction bundle.")
-	nodeAddressFlag := flag.String("node-address", "", "Node address used as caller. If not set, the first minipool's node address is used.")
+	flag.BoolVar(&data.Advisory, "advisory", false, "Monitoring only: build and simulate, then report whether the bundle passes the profit checks. Never prompts and never sends. The exit code and hooks report an opportunity as success.")
+	nodeAddressFlag := flag.String("node-address", "", "Node address used as caller. If not set, the first minipool's node address is used. Without --minipool(s), all exited minipools of this node are distributed.")
 	protocolFlag := flag.String("protocol", "best", "Protocol to use for arbitrage. Options: best, uniswap, paraswap")
 	receiverFlag := flag.String("receiver", "", "Receiver address for the arbitrage. If not set, the node address is used.")
 	nodeAddressPrivateKey := flag.String(
@@ -65,44 +117,173 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 		"",
 		"Private key for the node address used as caller. This can be used if the script should not use the RP daemon to sign transactions. (e.g. when using Allnode)",
 	)
+	flag.BoolVar(&data.DropFailing, "drop-failing", false, "If the distribute tx of a minipool reverts in the simulation, drop the minipool and rebuild the bundle with the remaining ones instead of aborting.")
+	flag.BoolVar(&data.ShowApr, "show-apr", false, "Print the profit after fees relative to the ETH bonded by the node, as an approximate APR boost. Advisory only.")
+	flag.BoolVar(&data.SeparateSigners, "separate-signers", false, "Require the arbitrage tx to be signed by --fee-payer and the distribute txs by the node. Checks the balance and pending txs of both accounts.")
+	feePayerFlag := flag.String("fee-payer", "", "Private key of a separate account that sends and pays for the arbitrage tx. The distribute txs are still sent by the node address.")
 	ratelimitFlag := flag.Int("ratelimit", 0, "Rate limit in milliseconds between each minipool distribution call. (default: 0)")
+	flag.Float64Var(&data.MaxPriceImpactPct, "max-price-impact-pct", 0, "Abort if the arbitrage swap would move the pool price by more than this percentage. (default: 0, disabled)")
+	flag.StringVar(&data.DumpBundle, "dump-bundle", "", "Write a canonical representation of the built bundle to --dump-bundle-file. Options: json")
+	flag.StringVar(&data.EmitSendBundle, "emit-sendbundle", "", "Write the eth_sendBundle JSON-RPC requests of the bundle, one per target block, to this file or \"-\" for stdout. Also written in --dry-run.")
+	flag.StringVar(&data.DumpBundleFile, "dump-bundle-file", "bundle.json", "Output file for --dump-bundle. (default: bundle.json)")
+	flag.StringVar(&data.EmitScript, "emit-script", "", "Write the flags and all resolved parameters of the run (minipools, addresses, block, fees) as JSON to this file, including a replay command. Private keys are redacted.")
+	flag.Float64Var(&data.ConfirmAmountAbove, "confirm-amount-above", 0, "Above this amount of ETH sent to rETH, the confirmation requires typing the amount instead of y/n. (default: 0, disabled)")
+	flag.StringVar(&data.OnSuccessCmd, "on-success-cmd", "", "Shell command executed after the bundle was included or the dry run completed. Run details are passed as RP_ARB_* environment variables.")
+	flag.StringVar(&data.OnFailureCmd, "on-failure-cmd", "", "Shell command executed after a failed run. Run details are passed as RP_ARB_* environment variables.")
+	flag.BoolVar(&data.Trace, "trace", false, "If the simulation fails, print a call trace of the failing transaction. Requires the debug namespace on the rpc.")
+	flag.Uint64Var(&data.GasBudget, "gas-budget", 0, "Maximum gas of the whole bundle. Only the minipools sending the most ETH to rETH that fit into the budget are distributed. (default: 0, unlimited)")
+	flag.IntVar(&data.BundleSize, "bundle-size", 0, "Split the minipools into multiple bundles with at most this many minipools each, sent one after another. (default: 0, single bundle)")
+	flag.IntVar(&data.MaxBundles, "max-bundles", 0, "With --bundle-size, only send the N most profitable bundles and defer the remaining minipools. (default: 0, unlimited)")
+	flag.StringVar(&data.StateFile, "state-file", "", "Record which minipools were distributed after each bundle. Use with --resume to continue a failed run.")
+	flag.BoolVar(&data.Resume, "resume", false, "Skip the minipools marked as distributed in --state-file.")
+	flag.StringVar(&data.JSONOutput, "json-output", "", "Write a machine-readable JSON report of the run to this file.")
+	flag.BoolVar(&data.SimulationDetails, "simulate-failure-details", false, "Print the per-transaction simulation results (gas used, status, value) even if the simulation succeeds.")
+	maxEthSpendFlag := flag.Float64("max-eth-spend", 0, "Maximum ETH leaving the node wallet across all bundles of this run, counted as value plus max fees of the node's txs. The bundle is not sent if it would be exceeded. (default: 0, unlimited)")
+	maxTotalValueFlag := flag.Float64("max-total-value", 0, "Maximum ETH at risk (distributed value plus fees) across all bundles of this run. Further bundles are not sent once it would be exceeded. (default: 0, unlimited)")
+	flag.DurationVar(&data.DedupeWindow, "dedupe-window", 0, "Refuse to submit a bundle for the same minipool set again within this window, unless the previous submission failed (e.g. 10m). (default: 0, disabled)")
+	flag.StringVar(&data.SubmissionLog, "submission-log", arbitrage.DefaultSubmissionLogPath(), "File used by --dedupe-window to remember recent submissions.")
+	flag.IntVar(&data.Precision, "precision", 6, "Number of decimals of the ETH amounts in the summary. (default: 6)")
+	flag.BoolVar(&data.Scientific, "scientific", false, "Print amounts too small for --precision in scientific notation instead of rounding them to zero.")
+	flag.Uint64Var(&data.ValidBlocks, "valid-blocks", arbitrage.DEFAULT_VALID_BLOCKS, "Number of upcoming blocks the bundle is submitted for at once. Higher values improve the chance of inclusion but wait longer.")
+	flag.Uint64Var(&data.Confirmations, "confirmations", 0, "After the inclusion, wait until the tx is this many blocks deep before reporting success. (default: 0, disabled)")
+	flag.DurationVar(&data.ConfirmationTimeout, "confirmation-timeout", 5*time.Minute, "Maximum time to wait for --confirmations and --inclusion-confirmations.")
+	flag.Uint64Var(&data.InclusionConfirmations, "inclusion-confirmations", 0, "Only count the bundle as included once its tx is found on chain with this many confirmations, instead of trusting the relay wait. (default: 0, disabled)")
+	flag.BoolVar(&data.DistributePriority, "distribute-priority", false, "Distribute even if the arbitrage tx would revert or not be profitable. The arbitrage profit may be forgone.")
+	flag.StringVar(&data.LogFile, "log-file", "", "Append the simulated and actual gas of each submitted bundle to this file (one JSON record per line). Summarize it with cmd/gasStats.")
+	flag.IntVar(&data.GasBufferPct, "gas-buffer-pct", arbitrage.DEFAULT_GAS_BUFFER_PCT, "Percentage added to the estimated gas of each distribute call for its gas limit.")
+	flag.StringVar(&data.Ledger, "ledger", "", "Append each included arbitrage bundle with the profit of its Arbitrage event, the fees paid and the running total to this CSV file.")
+	commissionTiersFlag := flag.String("commission-tiers", "", "Minimum estimated net profit in ETH per minipool by commission tier as commission%=minProfit pairs, e.g. 5=0.01,14=0.002. A minipool uses the tier with the highest commission not above its own, below all tiers it is kept. (default: disabled)")
+	flag.IntVar(&data.Top, "top", 0, "Print the minipools ranked by estimated net profit per gas and only build the best K. (default: 0, all)")
+	nonceFlag := flag.Int64("nonce", -1, "Nonce of the first bundle tx. If not set, the pending nonce of the node address is used.")
+	flag.Uint64Var(&data.NonceOffset, "nonce-offset", 0, "Added to the pending nonce of the node address, e.g. to not collide with txs sent by other tooling. (default: 0)")
+	flag.Float64Var(&data.MaxPoolSharePct, "max-pool-share-pct", arbitrage.DEFAULT_MAX_POOL_SHARE_PCT, "Abort if the uniswap swap would buy more than this percentage of the pool's rETH. 0 disables the check.")
+	flag.Float64Var(&data.FeeRefundPct, "fee-refund-pct", 0, "Assumed percentage of the priority fees refunded by the flashbots relay, shown as net profit in the summary. Checks are not affected. (default: 0, not shown)")
+	flag.Float64Var(&data.DonatePct, "donate-pct", 0, "Opt-in: percentage of the net profit sent to \"--donate-address\" by an extra tx in the bundle, deducted from the profit and shown in the summary. (default: 0, no donation)")
+	donateAddressFlag := flag.String("donate-address", "", "Recipient of the \"--donate-pct\" donation.")
+	flag.IntVar(&data.MinMinipools, "min-minipools", 0, "Refuse to run with fewer minipools, since the arbitrage gas is better amortized over a batch. (default: 0, disabled)")
+	flag.BoolVar(&data.Force, "force", false, "Run even if fewer than --min-minipools minipools are available or the expected profit is above --max-plausible-profit.")
+	minAbsoluteProfitFlag := flag.Float64("min-absolute-profit", 0, "Minimum net profit in ETH required by the profit check, after the bundle fees or with --ignore-distribute-cost after the arbitrage fees. (default: 0, disabled)")
+	maxPlausibleProfitFlag := flag.Float64("max-plausible-profit", arbitrage.DEFAULT_MAX_PLAUSIBLE_PROFIT, "Expected profit in ETH above which the bundle is not sent without --force, as it likely indicates a modeling error. 0 disables the check.")
+	chainlinkFeedFlag := flag.String("chainlink-feed", "", "Chainlink ETH/USD feed used to show the profit in USD as well, e.g. "+arbitrage.CHAINLINK_ETH_USD_FEED_MAINNET+" on mainnet.")
+	stateOverrideFlag := flag.String("state-override", "", "State overrides for the simulation as JSON or a path to a JSON file, in the eth_call format. The bundle is simulated on the rpc with eth_simulateV1 instead of the relay. Requires --dry-run.")
+	flag.Float64Var(&data.AutoThreshold, "auto-threshold", 0, "Require the expected profit to exceed this multiple of the expected fees at the current base fee, e.g. 1.5. (default: 0, disabled)")
+	flag.StringVar(&data.Label, "label", "", "Campaign label, e.g. \"Q1 exits\". Attached to the log records, --json-output, --log-file, the trace and the hook environment to group runs.")
+	flag.StringVar(&data.ExplorerUrl, "explorer-url", "", "Block explorer used for the tx links, e.g. https://beaconcha.in. (default: etherscan on mainnet, explorer.holesky.io on holesky)")
+	flag.StringVar(&data.OtelEndpoint, "otel-endpoint", "", "OpenTelemetry collector (OTLP/HTTP), e.g. http://localhost:4318. If set, a trace of the run is exported to it.")
+	flag.BoolVar(&data.Strict, "strict", false, "Abort instead of warning if the node address has pending transactions in the mempool.")
+	flag.BoolVar(&data.Timings, "timings", false, "Print how long verifying the input, building, simulating and waiting for inclusion took, also added to \"--json-output\".")
+	flag.BoolVar(&data.VerifyBytecode, "verify-bytecode", false, "Refuse to run if the arbitrage contract code does not match the expected hash or a minipool is not registered in Rocket Pool.")
+	arbitrageCodeHashFlag := flag.String("arbitrage-code-hash", "", "Expected code hash of the arbitrage contract for \"--verify-bytecode\". (default: hash of the compiled contract shipped with the tool)")
+	flag.Uint64Var(&data.SimulationOffset, "simulation-offset", 1, "Simulate the bundle this many blocks after the current block, 1 is the first target block. 0 simulates in the current block.")
+	flag.BoolVar(&data.NoBroadcast, "no-broadcast", false, "Only submit the bundle to the default flashbots relay instead of broadcasting it to all known builders.")
+	flag.StringVar(&data.BeaconUrl, "beacon-url", "", "Beacon node RPC endpoint, e.g. http://localhost:5052. If set, the minipool balances are cross-checked with their validators to warn about pending withdrawals.")
+	flag.DurationVar(&data.MinMinipoolAge, "min-minipool-age", 0, "Only distribute minipools whose validator exited at least this long ago, e.g. 720h. Requires --beacon-url. (default: 0, disabled)")
+	flag.DurationVar(&data.MaxMinipoolAge, "max-minipool-age", 0, "Only distribute minipools whose validator exited at most this long ago, e.g. 48h. Requires --beacon-url. (default: 0, disabled)")
+	feeLadderFlag := flag.String("fee-ladder", "", "Comma-separated ascending tips in gwei, e.g. \"2,4,8\". The bundle is resubmitted with the next tip until it is included. Each step is sent for one block unless --valid-blocks is set.")
+	maxPriorityFeeFlag := flag.Float64("max-priority-fee", 0, "Max tip in gwei, caps the suggested tip and the --fee-ladder steps. (default: 0, disabled)")
+	feeCapFlag := flag.Float64("fee-cap", 0, "Fixed fee cap (max fee per gas) in gwei, replaces the boosted suggested gas price. (default: 0, disabled)")
+	tipCapFlag := flag.Float64("tip-cap", 0, "Fixed tip (max priority fee per gas) in gwei, replaces the suggested tip. (default: 0, disabled)")
+	txTypeFlag := flag.String("tx-type", "1559", "Transaction type of the bundle. Options: 1559, legacy")
+	clientTagFlag := flag.String("client-tag", arbitrage.DefaultClientTag(), "User-Agent sent with all requests to the flashbots relay.")
+	relayUrlFlag := flag.String("relay-url", "", "Flashbots JSON-RPC endpoint to submit to instead of the default relay, e.g. a self-hosted relay proxy. Checked for connectivity on startup.")
+	relayAuthHeaderFlag := flag.String("relay-auth-header", "", "Header sent with all signed requests to --relay-url, as \"Name: value\".")
+	printProfitOnlyFlag := flag.Bool("print-profit-only", false, "Only print the expected profit after fees in ETH to stdout, all other output goes to stderr. Never sends the bundle, the exit code is 0 if the profit check passes.")
+	flag.DurationVar(&data.MaxRuntime, "max-runtime", 0, "Hard deadline for the whole invocation, e.g. 10m. Pending waits are cancelled and the tool exits with code 124. (default: 0, disabled)")
+	rpcRateLimitFlag := flag.Float64("rpc-rate-limit", 0, "Maximum number of RPC requests per second, applied to all eth1 client calls. (default: 0, unlimited)")
 
 	flag.Parse()
 
-	if *debugFlag {
+	data.Flags = map[string]string{}
+	flag.Visit(func(f *flag.Flag) {
+		data.Flags[f.Name] = f.Value.String()
+	})
+
+	if data.MaxRuntime < 0 {
+		return nil, errors.New("\"--max-runtime\" must not be negative")
+	}
+	if data.MaxRuntime > 0 {
+		// the deadline also covers the rpc calls made while parsing the input
+		data.Deadline = time.Now().Add(data.MaxRuntime)
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, data.Deadline)
+		defer cancel()
+	}
+
+	if *printProfitOnlyFlag {
+		// the profit is the only value on stdout, everything else is moved to stderr
+		data.ProfitOutput = os.Stdout
+		os.Stdout = os.Stderr
+		data.DryRun = true
+	}
+
+	if *quietFlag {
+		if *debugFlag {
+			return nil, errors.New("\"--quiet\" and \"--debug\" are mutually exclusive")
+		}
+		// a prompt nobody sees would block forever
+		if !data.SkipConfirmation && !data.DryRun && !data.Advisory {
+			return nil, errors.New("\"--quiet\" requires \"--skip-confirmation\", \"--dry-run\" or \"--advisory\"")
+		}
+
+		// all human output goes to stdout, the logs and errors stay on stderr
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to open null device"), err)
+		}
+		os.Stdout = devNull
+		slog.SetLogLoggerLevel(slog.LevelWarn)
+	} else if *debugFlag {
 		slog.SetLogLoggerLevel(slog.LevelDebug)
 	} else {
 		slog.SetLogLoggerLevel(slog.LevelInfo)
 	}
 
+	logger.Debug("maxRuntime", slog.Duration("maxRuntime", data.MaxRuntime))
+
 	data.Command = *commandFlag
 	logger.Debug("command", slog.String("command", data.Command))
 
-	if *minipoolFlag == "" && *minipoolsFlag == "" {
-		return nil, errors.New("\"--minipool\" or \"--minipools\" is required")
+	// without a minipool list, the minipools of the node are distributed
+	enumerateNodeMinipools := *minipoolFlag == "" && *minipoolsFlag == ""
+	if enumerateNodeMinipools && *nodeAddressFlag == "" && *nodeAddressPrivateKey == "" {
+		return nil, errors.New("\"--minipool\", \"--minipools\" or \"--node-address\" is required")
 	}
 
 	data.MinipoolAddresses = []common.Address{}
 	if *minipoolFlag != "" {
-		minipoolStr := strings.Trim(*minipoolFlag, " \"'")
-		if !common.IsHexAddress(minipoolStr) {
-			return nil, fmt.Errorf("minipool address _%s_ is invalid", minipoolStr)
+		minipool, err := arbitrage.ParseAddress(logger, "minipool", *minipoolFlag)
+		if err != nil {
+			return nil, err
 		}
 
-		data.MinipoolAddresses = append(data.MinipoolAddresses, common.HexToAddress(minipoolStr))
-		logger.Debug("minipool", slog.String("minipool", common.HexToAddress(minipoolStr).Hex()))
+		data.MinipoolAddresses = append(data.MinipoolAddresses, minipool)
+		logger.Debug("minipool", slog.String("minipool", minipool.Hex()))
 	}
 
 	if *minipoolsFlag != "" {
 		minipools := strings.Split(*minipoolsFlag, ",")
 		for _, minipool := range minipools {
-			minipool = strings.Trim(minipool, " \"'")
-			if !common.IsHexAddress(minipool) {
-				return nil, fmt.Errorf("minipool address _%s_ is invalid", minipool)
+			minipoolAddress, err := arbitrage.ParseAddress(logger, "minipool", minipool)
+			if err != nil {
+				return nil, err
+			}
+
+			data.MinipoolAddresses = append(data.MinipoolAddresses, minipoolAddress)
+			logger.Debug("minipool", slog.String("minipool", minipoolAddress.Hex()))
+		}
+	}
+
+	if *excludeMinipoolsFlag != "" {
+		minipools := strings.Split(*excludeMinipoolsFlag, ",")
+		for _, minipool := range minipools {
+			minipoolAddress, err := arbitrage.ParseAddress(logger, "excluded minipool", minipool)
+			if err != nil {
+				return nil, err
 			}
 
-			data.MinipoolAddresses = append(data.MinipoolAddresses, common.HexToAddress(minipool))
-			logger.Debug("minipool", slog.String("minipool", common.HexToAddress(minipool).Hex()))
+			data.ExcludeMinipools = append(data.ExcludeMinipools, minipoolAddress)
+			logger.Debug("excluded minipool", slog.String("minipool", minipoolAddress.Hex()))
 		}
 	}
 
@@ -122,7 +303,34 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 		url = *rpcFlag
 	}
 
-	data.Client, err = ethclient.DialContext(ctx, url)
+	if *rpcRateLimitFlag < 0 {
+		return nil, errors.New("\"--rpc-rate-limit\" must not be negative")
+	}
+
+	// has to be set before any http client is created
+	arbitrage.SetRelayClientTag(strings.TrimSpace(*clientTagFlag))
+	logger.Debug("clientTag", slog.String("clientTag", *clientTagFlag))
+
+	// the auth header must not leak to the public relay
+	if *relayAuthHeaderFlag != "" && *relayUrlFlag == "" {
+		return nil, errors.New("\"--relay-auth-header\" requires \"--relay-url\"")
+	}
+	if *relayAuthHeaderFlag != "" {
+		err := arbitrage.SetRelayAuthHeader(*relayAuthHeaderFlag)
+		if err != nil {
+			return nil, errors.Join(errors.New("invalid \"--relay-auth-header\""), err)
+		}
+	}
+
+	urls := strings.Split(url, ",")
+	if len(urls) > 1 {
+		for i := range urls {
+			urls[i] = strings.TrimSpace(urls[i])
+		}
+		data.Client, err = arbitrage.DialFailoverClient(ctx, logger, urls, *rpcRateLimitFlag)
+	} else {
+		data.Client, err = arbitrage.DialClient(ctx, url, *rpcRateLimitFlag)
+	}
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to connect to rpc"), err)
 	}
@@ -138,7 +346,20 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 		return nil, errors.New("only mainnet and holesky are supported")
 	}
 
-	logger.Debug("rpc connected and verified")
+	logger.Debug("rpc connected and verified", slog.Int("rpcEndpoints", len(urls)), slog.Float64("rpcRateLimit", *rpcRateLimitFlag))
+
+	if *relayUrlFlag != "" {
+		err = arbitrage.SetRelayUrl(data.NetworkId, *relayUrlFlag)
+		if err != nil {
+			return nil, err
+		}
+
+		err = arbitrage.CheckRelay(*relayUrlFlag, *relayAuthHeaderFlag)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to verify relay connection"), err)
+		}
+		logger.Debug("relay connected and verified", slog.String("relayUrl", *relayUrlFlag), slog.Bool("authHeader", *relayAuthHeaderFlag != ""))
+	}
 
 	var privateKey *ecdsa.PrivateKey
 	if *SercherPrivateKeyFlag != "" {
@@ -182,12 +403,32 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 		fmt.Printf("Using provided ECDSA private key for node address (Address: %s)\n", data.NodeAddress.Hex())
 	}
 
-	if *nodeAddressFlag != "" {
-		if !common.IsHexAddress(*nodeAddressFlag) {
-			return nil, errors.New("node address is invalid")
+	if *feePayerFlag != "" {
+		feePayerKey, err := crypto.HexToECDSA(strings.TrimPrefix(*feePayerFlag, "0x"))
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to parse ECDSA private key for fee payer"), err)
+		}
+
+		data.FeePayerPrivateKey = feePayerKey
+		data.FeePayerAddress = new(common.Address)
+		*data.FeePayerAddress = crypto.PubkeyToAddress(feePayerKey.PublicKey)
+		fmt.Printf("Using provided ECDSA private key for fee payer (Address: %s)\n", data.FeePayerAddress.Hex())
+
+		// the burn tx of local rETH burns the node's rETH, only the node can send it
+		if data.LocalReth {
+			return nil, errors.New("\"--fee-payer\" cannot be used with \"--local-reth\"")
 		}
+	}
+
+	if data.SeparateSigners && data.FeePayerPrivateKey == nil {
+		return nil, errors.New("\"--separate-signers\" requires \"--fee-payer\" to sign the arbitrage tx")
+	}
 
-		nodeAddress := common.HexToAddress(*nodeAddressFlag)
+	if *nodeAddressFlag != "" {
+		nodeAddress, err := arbitrage.ParseAddress(logger, "node", *nodeAddressFlag)
+		if err != nil {
+			return nil, err
+		}
 
 		if *nodeAddressPrivateKey != "" {
 			// sanity check in case user provided a private key
@@ -211,12 +452,21 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 		return nil, errors.New("invalid protocol - Options: best, uniswap, paraswap")
 	}
 
+	switch *txTypeFlag {
+	case "1559":
+		data.TxType = arbitrage.TxType1559
+	case "legacy":
+		data.TxType = arbitrage.TxTypeLegacy
+	default:
+		return nil, errors.New("invalid tx type - Options: 1559, legacy")
+	}
+	logger.Debug("txType", slog.String("txType", string(data.TxType)))
+
 	if *receiverFlag != "" {
-		if !common.IsHexAddress(*receiverFlag) {
-			return nil, errors.New("receiver address is invalid")
+		receiverAddress, err := arbitrage.ParseAddress(logger, "receiver", *receiverFlag)
+		if err != nil {
+			return nil, err
 		}
-
-		receiverAddress := common.HexToAddress(*receiverFlag)
 		data.ReceiverAddress = &receiverAddress
 		logger.Debug("receiverAddress", slog.String("receiverAddress", receiverAddress.Hex()))
 	}
@@ -228,6 +478,13 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 
 	logger.Debug("localReth", slog.Bool("localReth", data.LocalReth))
 	logger.Debug("dryRunFlag", slog.Bool("dryRunFlag", data.DryRun))
+
+	if data.Advisory && (data.DryRun || data.LocalReth) {
+		return nil, errors.New("\"--advisory\" cannot be combined with \"--dry-run\", \"--print-profit-only\" or \"--local-reth\"")
+	}
+	logger.Debug("advisory", slog.Bool("advisory", data.Advisory))
+	logger.Debug("traceFlag", slog.Bool("traceFlag", data.Trace))
+	logger.Debug("simulateFailureDetailsFlag", slog.Bool("simulateFailureDetailsFlag", data.SimulationDetails))
 	logger.Debug("skipConfirmation", slog.Bool("skipConfirmation", data.SkipConfirmation))
 	logger.Debug("checkProfitFlag", slog.Bool("checkProfitFlag", data.CheckProfit))
 	logger.Debug("ignoreDistributeCostFlag",
@@ -235,6 +492,33 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 		slog.Bool("ignoreDistributeCostFlag", data.CheckProfitIgnoreDistributeCost),
 	)
 
+	if data.CheckProfitBoth && data.CheckProfitIgnoreDistributeCost {
+		return nil, errors.New("\"--check-profit-both\" cannot be combined with \"--ignore-distribute-cost\"")
+	}
+	logger.Debug("checkProfitBoth", slog.Bool("checkProfitBoth", data.CheckProfitBoth))
+
+	if data.AutoThreshold != 0 {
+		if data.AutoThreshold < 1 {
+			return nil, errors.New("\"--auto-threshold\" must be at least 1, a lower margin accepts a loss at the expected fees")
+		}
+		if data.LocalReth {
+			return nil, errors.New("\"--auto-threshold\" requires the arbitrage, local rETH has no profit")
+		}
+	}
+	logger.Debug("autoThreshold", slog.Float64("autoThreshold", data.AutoThreshold))
+
+	if *stateOverrideFlag != "" {
+		// a bundle that only works on hypothetical state must never be sent
+		if !data.DryRun {
+			return nil, errors.New("\"--state-override\" requires \"--dry-run\"")
+		}
+		data.StateOverride, err = arbitrage.LoadStateOverride(*stateOverrideFlag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	logger.Debug("stateOverride", slog.Int("accounts", len(data.StateOverride)))
+
 	if data.NetworkId == 17000 && !data.LocalReth {
 		return nil, errors.New("holesky does not support flashloan's")
 	}
@@ -242,5 +526,305 @@ func parseInput(ctx context.Context, logger *slog.Logger) (data *arbitrage.DataI
 	data.Ratelimit = *ratelimitFlag
 	logger.Debug("ratelimit", slog.Int("ratelimit", data.Ratelimit))
 
+	if enumerateNodeMinipools {
+		data.MinipoolAddresses, err = arbitrage.GetDistributableNodeMinipools(ctx, logger, data)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to get node minipools"), err)
+		}
+		if len(data.MinipoolAddresses) == 0 {
+			if *printProfitOnlyFlag {
+				return nil, fmt.Errorf("node %s has no exited minipools to distribute", data.NodeAddress.Hex())
+			}
+			return nil, fmt.Errorf("%w: node %s has no exited minipools to distribute", arbitrage.ErrNothingToDistribute, data.NodeAddress.Hex())
+		}
+
+		fmt.Printf("Found %d exited minipools of node %s:\n", len(data.MinipoolAddresses), data.NodeAddress.Hex())
+		for _, minipool := range data.MinipoolAddresses {
+			fmt.Printf("    %s\n", minipool.Hex())
+		}
+		fmt.Println()
+	}
+
+	if data.MaxPriceImpactPct < 0 {
+		return nil, errors.New("\"--max-price-impact-pct\" must not be negative")
+	}
+	logger.Debug("maxPriceImpactPct", slog.Float64("maxPriceImpactPct", data.MaxPriceImpactPct))
+
+	if data.DumpBundle != "" && data.DumpBundle != "json" {
+		return nil, errors.New("invalid dump format - Options: json")
+	}
+	if data.ConfirmAmountAbove < 0 {
+		return nil, errors.New("\"--confirm-amount-above\" must not be negative")
+	}
+	if data.BundleSize < 0 || data.MaxBundles < 0 {
+		return nil, errors.New("\"--bundle-size\" and \"--max-bundles\" must not be negative")
+	}
+	if data.MaxBundles > 0 && data.BundleSize == 0 {
+		return nil, errors.New("\"--max-bundles\" requires \"--bundle-size\"")
+	}
+	if data.Resume && data.StateFile == "" {
+		return nil, errors.New("\"--resume\" requires \"--state-file\"")
+	}
+	if *maxEthSpendFlag < 0 {
+		return nil, errors.New("\"--max-eth-spend\" must not be negative")
+	}
+	if *maxEthSpendFlag > 0 {
+		data.MaxEthSpend, _ = new(big.Float).Mul(big.NewFloat(*maxEthSpendFlag), big.NewFloat(1e18)).Int(nil)
+	}
+	logger.Debug("maxEthSpend", slog.Float64("maxEthSpend", *maxEthSpendFlag))
+
+	if *maxTotalValueFlag < 0 {
+		return nil, errors.New("\"--max-total-value\" must not be negative")
+	}
+	if *maxTotalValueFlag > 0 {
+		data.MaxTotalValue, _ = new(big.Float).Mul(big.NewFloat(*maxTotalValueFlag), big.NewFloat(1e18)).Int(nil)
+	}
+	logger.Debug("maxTotalValue", slog.Float64("maxTotalValue", *maxTotalValueFlag))
+
+	if *minAbsoluteProfitFlag < 0 {
+		return nil, errors.New("\"--min-absolute-profit\" must not be negative")
+	}
+	if *minAbsoluteProfitFlag > 0 {
+		data.MinAbsoluteProfit, _ = new(big.Float).Mul(big.NewFloat(*minAbsoluteProfitFlag), big.NewFloat(1e18)).Int(nil)
+	}
+	logger.Debug("minAbsoluteProfit", slog.Float64("minAbsoluteProfit", *minAbsoluteProfitFlag))
+
+	if *maxPlausibleProfitFlag < 0 {
+		return nil, errors.New("\"--max-plausible-profit\" must not be negative")
+	}
+	if *maxPlausibleProfitFlag > 0 {
+		data.MaxPlausibleProfit, _ = new(big.Float).Mul(big.NewFloat(*maxPlausibleProfitFlag), big.NewFloat(1e18)).Int(nil)
+	}
+	logger.Debug("maxPlausibleProfit", slog.Float64("maxPlausibleProfit", *maxPlausibleProfitFlag))
+
+	// wei has 18 decimals, more are never meaningful
+	if data.Precision < 0 || data.Precision > 18 {
+		return nil, errors.New("\"--precision\" must be between 0 and 18")
+	}
+	logger.Debug("precision", slog.Int("precision", data.Precision), slog.Bool("scientific", data.Scientific))
+
+	if data.ValidBlocks == 0 || data.ValidBlocks > arbitrage.MAX_VALID_BLOCKS {
+		return nil, fmt.Errorf("\"--valid-blocks\" must be between 1 and %d", arbitrage.MAX_VALID_BLOCKS)
+	}
+	logger.Debug("validBlocks", slog.Uint64("validBlocks", data.ValidBlocks))
+
+	if data.SimulationOffset > arbitrage.MAX_VALID_BLOCKS {
+		return nil, fmt.Errorf("\"--simulation-offset\" must be at most %d", arbitrage.MAX_VALID_BLOCKS)
+	}
+	if data.OtelEndpoint != "" && !strings.HasPrefix(data.OtelEndpoint, "http://") && !strings.HasPrefix(data.OtelEndpoint, "https://") {
+		return nil, errors.New("\"--otel-endpoint\" must be an http(s) url")
+	}
+	logger.Debug("otelEndpoint", slog.String("otelEndpoint", data.OtelEndpoint))
+
+	if data.ExplorerUrl != "" && !strings.HasPrefix(data.ExplorerUrl, "http://") && !strings.HasPrefix(data.ExplorerUrl, "https://") {
+		return nil, errors.New("\"--explorer-url\" must be an http(s) url")
+	}
+	data.ExplorerUrl = strings.TrimSuffix(data.ExplorerUrl, "/")
+	logger.Debug("explorerUrl", slog.String("explorerUrl", data.ExplorerUrl))
+
+	data.Label = strings.TrimSpace(data.Label)
+	logger.Debug("label", slog.String("label", data.Label))
+	logger.Debug("strict", slog.Bool("strict", data.Strict))
+	logger.Debug("timings", slog.Bool("timings", data.Timings))
+
+	if *arbitrageCodeHashFlag != "" {
+		if !data.VerifyBytecode {
+			return nil, errors.New("\"--arbitrage-code-hash\" requires \"--verify-bytecode\"")
+		}
+		codeHash := strings.TrimSpace(*arbitrageCodeHashFlag)
+		if !strings.HasPrefix(codeHash, "0x") || len(codeHash) != 2+2*common.HashLength {
+			return nil, errors.New("\"--arbitrage-code-hash\" must be a 0x prefixed 32 byte hex string")
+		}
+		hash := common.HexToHash(codeHash)
+		data.ArbitrageCodeHash = &hash
+	}
+	logger.Debug("verifyBytecode", slog.Bool("verifyBytecode", data.VerifyBytecode), slog.String("arbitrageCodeHash", *arbitrageCodeHashFlag))
+	logger.Debug("simulationOffset", slog.Uint64("simulationOffset", data.SimulationOffset))
+
+	if *feeLadderFlag != "" {
+		for _, step := range strings.Split(*feeLadderFlag, ",") {
+			tipGwei, err := strconv.ParseFloat(strings.TrimSpace(step), 64)
+			if err != nil || tipGwei <= 0 {
+				return nil, fmt.Errorf("invalid fee ladder step %q, expected a positive tip in gwei", step)
+			}
+			tip, _ := new(big.Float).Mul(big.NewFloat(tipGwei), big.NewFloat(1e9)).Int(nil)
+			if len(data.FeeLadder) > 0 && tip.Cmp(data.FeeLadder[len(data.FeeLadder)-1]) <= 0 {
+				return nil, errors.New("\"--fee-ladder\" steps must be ascending")
+			}
+			data.FeeLadder = append(data.FeeLadder, tip)
+		}
+
+		// each step is a retry block of its own unless set explicitly
+		if _, ok := data.Flags["valid-blocks"]; !ok {
+			data.ValidBlocks = 1
+		}
+	}
+	if *maxPriorityFeeFlag < 0 {
+		return nil, errors.New("\"--max-priority-fee\" must not be negative")
+	}
+	if *maxPriorityFeeFlag > 0 {
+		data.MaxPriorityFee, _ = new(big.Float).Mul(big.NewFloat(*maxPriorityFeeFlag), big.NewFloat(1e9)).Int(nil)
+	}
+	if *feeCapFlag < 0 || *tipCapFlag < 0 {
+		return nil, errors.New("\"--fee-cap\" and \"--tip-cap\" must not be negative")
+	}
+	if *feeCapFlag > 0 {
+		data.FeeCap, _ = new(big.Float).Mul(big.NewFloat(*feeCapFlag), big.NewFloat(1e9)).Int(nil)
+	}
+	if *tipCapFlag > 0 {
+		data.TipCap, _ = new(big.Float).Mul(big.NewFloat(*tipCapFlag), big.NewFloat(1e9)).Int(nil)
+	}
+	if data.TipCap != nil && len(data.FeeLadder) > 0 {
+		return nil, errors.New("\"--tip-cap\" cannot be combined with \"--fee-ladder\", the ladder sets the tip")
+	}
+	if data.FeeCap != nil && data.TipCap != nil && data.TipCap.Cmp(data.FeeCap) > 0 {
+		return nil, errors.New("\"--tip-cap\" must not be above \"--fee-cap\"")
+	}
+	if data.FeeCap != nil && len(data.FeeLadder) > 0 && data.FeeLadder[len(data.FeeLadder)-1].Cmp(data.FeeCap) > 0 {
+		return nil, errors.New("\"--fee-ladder\" steps must not be above \"--fee-cap\"")
+	}
+	logger.Debug("feeLadder", slog.String("feeLadder", *feeLadderFlag), slog.Float64("maxPriorityFee", *maxPriorityFeeFlag))
+	logger.Debug("fixed fees", slog.Float64("feeCap", *feeCapFlag), slog.Float64("tipCap", *tipCapFlag))
+
+	if *printProfitOnlyFlag {
+		switch {
+		case data.LocalReth:
+			return nil, errors.New("\"--print-profit-only\" requires the arbitrage, local rETH has no profit")
+		case !data.CheckProfit:
+			return nil, errors.New("\"--print-profit-only\" uses the profit check for the exit code, it cannot be combined with \"--check-profit=false\"")
+		case data.BundleSize > 0 || len(data.FeeLadder) > 0:
+			return nil, errors.New("\"--print-profit-only\" cannot be combined with \"--bundle-size\" or \"--fee-ladder\"")
+		}
+	}
+	logger.Debug("printProfitOnly", slog.Bool("printProfitOnly", *printProfitOnlyFlag))
+
+	if (data.Confirmations > 0 || data.InclusionConfirmations > 0) && data.ConfirmationTimeout <= 0 {
+		return nil, errors.New("\"--confirmation-timeout\" must be positive")
+	}
+	logger.Debug("confirmations", slog.Uint64("confirmations", data.Confirmations), slog.Uint64("inclusionConfirmations", data.InclusionConfirmations), slog.Duration("confirmationTimeout", data.ConfirmationTimeout))
+
+	if data.DistributePriority && data.LocalReth {
+		return nil, errors.New("\"--distribute-priority\" has no effect with \"--local-reth\"")
+	}
+	logger.Debug("distributePriority", slog.Bool("distributePriority", data.DistributePriority))
+
+	logger.Debug("logFile", slog.String("logFile", data.LogFile))
+	logger.Debug("ledger", slog.String("ledger", data.Ledger))
+
+	if data.GasBufferPct < 0 {
+		return nil, errors.New("\"--gas-buffer-pct\" must not be negative")
+	}
+	logger.Debug("gasBufferPct", slog.Int("gasBufferPct", data.GasBufferPct))
+
+	logger.Debug("noBroadcast", slog.Bool("noBroadcast", data.NoBroadcast))
+
+	data.BeaconUrl = strings.TrimSuffix(data.BeaconUrl, "/")
+	logger.Debug("beaconUrl", slog.String("beaconUrl", data.BeaconUrl))
+
+	if data.MinMinipoolAge < 0 || data.MaxMinipoolAge < 0 {
+		return nil, errors.New("\"--min-minipool-age\" and \"--max-minipool-age\" must not be negative")
+	}
+	if (data.MinMinipoolAge > 0 || data.MaxMinipoolAge > 0) && data.BeaconUrl == "" {
+		return nil, errors.New("\"--min-minipool-age\" and \"--max-minipool-age\" require \"--beacon-url\" to read the exit epochs")
+	}
+	if data.MaxMinipoolAge > 0 && data.MinMinipoolAge > data.MaxMinipoolAge {
+		return nil, errors.New("\"--min-minipool-age\" must not be above \"--max-minipool-age\"")
+	}
+	logger.Debug("minipoolAge", slog.Duration("min", data.MinMinipoolAge), slog.Duration("max", data.MaxMinipoolAge))
+
+	if *chainlinkFeedFlag != "" {
+		chainlinkFeed, err := arbitrage.ParseAddress(logger, "chainlink feed", *chainlinkFeedFlag)
+		if err != nil {
+			return nil, err
+		}
+		data.ChainlinkFeed = &chainlinkFeed
+		logger.Debug("chainlinkFeed", slog.String("chainlinkFeed", chainlinkFeed.Hex()))
+	}
+
+	if data.MinMinipools < 0 {
+		return nil, errors.New("\"--min-minipools\" must not be negative")
+	}
+	logger.Debug("minMinipools", slog.Int("minMinipools", data.MinMinipools), slog.Bool("force", data.Force))
+
+	if *commissionTiersFlag != "" {
+		if data.LocalReth {
+			return nil, errors.New("\"--commission-tiers\" has no effect with \"--local-reth\", there is no arbitrage profit")
+		}
+		data.CommissionTiers, err = arbitrage.ParseCommissionTiers(*commissionTiersFlag)
+		if err != nil {
+			return nil, errors.Join(errors.New("invalid \"--commission-tiers\""), err)
+		}
+	}
+	logger.Debug("commissionTiers", slog.String("commissionTiers", *commissionTiersFlag))
+
+	if data.FeeRefundPct < 0 || data.FeeRefundPct > 100 {
+		return nil, errors.New("\"--fee-refund-pct\" must be between 0 and 100")
+	}
+	logger.Debug("feeRefundPct", slog.Float64("feeRefundPct", data.FeeRefundPct))
+
+	if data.DonatePct < 0 || data.DonatePct > 100 {
+		return nil, errors.New("\"--donate-pct\" must be between 0 and 100")
+	}
+	if data.DonatePct > 0 {
+		if *donateAddressFlag == "" {
+			return nil, errors.New("\"--donate-pct\" requires \"--donate-address\"")
+		}
+		if data.LocalReth {
+			return nil, errors.New("\"--donate-pct\" has no effect with \"--local-reth\", there is no arbitrage profit")
+		}
+		// the transfer would not revert with the arbitrage and be paid from the existing balance
+		if data.DistributePriority {
+			return nil, errors.New("\"--donate-pct\" cannot be combined with \"--distribute-priority\"")
+		}
+		donateAddress, err := arbitrage.ParseAddress(logger, "donate", *donateAddressFlag)
+		if err != nil {
+			return nil, err
+		}
+		data.DonateAddress = &donateAddress
+	} else if *donateAddressFlag != "" {
+		return nil, errors.New("\"--donate-address\" requires \"--donate-pct\"")
+	}
+	logger.Debug("donate", slog.Float64("donatePct", data.DonatePct), slog.String("donateAddress", *donateAddressFlag))
+
+	if data.MaxPoolSharePct < 0 || data.MaxPoolSharePct > 100 {
+		return nil, errors.New("\"--max-pool-share-pct\" must be between 0 and 100")
+	}
+	logger.Debug("maxPoolSharePct", slog.Float64("maxPoolSharePct", data.MaxPoolSharePct))
+
+	if *nonceFlag >= 0 {
+		if data.NonceOffset > 0 {
+			return nil, errors.New("\"--nonce\" and \"--nonce-offset\" are mutually exclusive")
+		}
+		nonce := uint64(*nonceFlag)
+		data.Nonce = &nonce
+	} else if *nonceFlag != -1 {
+		return nil, errors.New("\"--nonce\" must not be negative")
+	}
+	logger.Debug("nonce", slog.Int64("nonce", *nonceFlag), slog.Uint64("nonceOffset", data.NonceOffset))
+
+	if data.Top < 0 {
+		return nil, errors.New("\"--top\" must not be negative")
+	}
+	if data.Top > 0 && data.LocalReth {
+		return nil, errors.New("\"--top\" ranks by arbitrage profit and cannot be used with \"--local-reth\"")
+	}
+	if data.Top > 0 && data.BundleSize > 0 {
+		return nil, errors.New("\"--top\" cannot be combined with \"--bundle-size\", use \"--max-bundles\" to send only the best bundles")
+	}
+	logger.Debug("top", slog.Int("top", data.Top))
+
+	logger.Debug("dedupeWindow", slog.Duration("dedupeWindow", data.DedupeWindow), slog.String("submissionLog", data.SubmissionLog))
+
+	logger.Debug("jsonOutput", slog.String("jsonOutput", data.JSONOutput))
+	logger.Debug("stateFile", slog.String("stateFile", data.StateFile), slog.Bool("resume", data.Resume))
+
+	logger.Debug("bundleSize", slog.Int("bundleSize", data.BundleSize), slog.Int("maxBundles", data.MaxBundles))
+
+	logger.Debug("gasBudget", slog.Uint64("gasBudget", data.GasBudget))
+	logger.Debug("confirmAmountAbove", slog.Float64("confirmAmountAbove", data.ConfirmAmountAbove))
+
+	logger.Debug("emitScript", slog.String("emitScript", data.EmitScript))
+	logger.Debug("dumpBundle", slog.String("dumpBundle", data.DumpBundle), slog.String("dumpBundleFile", data.DumpBundleFile))
+
 	return data, nil
 }
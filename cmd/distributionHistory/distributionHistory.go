@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"rocketpoolArbitrage/arbitrage"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+type input struct {
+	nodeAddress common.Address
+	rpcUrl      string
+	fromBlock   uint64
+	json        bool
+}
+
+func main() {
+	in, err := parseInput()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ctx := context.Background()
+	client, err := ethclient.DialContext(ctx, in.rpcUrl)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	networkID, err := client.NetworkID(ctx)
+	if err != nil {
+		fmt.Println(errors.Join(errors.New("failed to verify client connection"), err))
+		return
+	}
+
+	latest, err := client.BlockNumber(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fromBlock := in.fromBlock
+	if fromBlock == 0 && latest > arbitrage.HISTORY_DEFAULT_BLOCKS {
+		fromBlock = latest - arbitrage.HISTORY_DEFAULT_BLOCKS
+	}
+	if fromBlock > latest {
+		fmt.Printf("\"--from-block\" %d is after the latest block %d\n", fromBlock, latest)
+		return
+	}
+
+	minipools, err := arbitrage.GetNodeMinipools(ctx, client, networkID.Uint64(), in.nodeAddress, 0)
+	if err != nil {
+		fmt.Println(errors.Join(errors.New("failed to get the node's minipools"), err))
+		return
+	}
+
+	distributions, err := arbitrage.GetDistributionHistory(ctx, client, minipools, fromBlock, latest)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if in.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(distributions)
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	fmt.Printf("Scanned blocks %d to %d for %d minipools of node %s, found %d distributions.\n\n", fromBlock, latest, len(minipools), in.nodeAddress.Hex(), len(distributions))
+	if len(distributions) == 0 {
+		return
+	}
+
+	fmt.Printf("    %-9s %-20s %-42s %12s %12s  %s\n", "Block", "Time", "Minipool", "Node ETH", "User ETH", "Tx")
+	nodeTotal := new(big.Int)
+	userTotal := new(big.Int)
+	for _, distribution := range distributions {
+		fmt.Printf("    %-9d %-20s %-42s %12.6f %12.6f  %s\n",
+			distribution.Block,
+			time.Unix(int64(distribution.Time), 0).UTC().Format("2006-01-02 15:04:05"),
+			distribution.Minipool.Hex(),
+			weiToEth(distribution.NodeAmount),
+			weiToEth(distribution.UserAmount),
+			distribution.TxHash.Hex(),
+		)
+		nodeTotal.Add(nodeTotal, distribution.NodeAmount)
+		userTotal.Add(userTotal, distribution.UserAmount)
+	}
+	fmt.Printf("\nTotal: %.6f ETH to the node, %.6f ETH to rETH\n", weiToEth(nodeTotal), weiToEth(userTotal))
+}
+
+func weiToEth(amount *big.Int) float64 {
+	value, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(big.NewInt(1e18))).Float64()
+	return value
+}
+
+func parseInput() (input, error) {
+	nodeAddressFlag := flag.String("node-address", "", "Node address whose minipools are scanned")
+	rpcFlag := flag.String("rpc", "http://localhost:8545", "Ethereum RPC endpoint for all on-chain calls. (default: http://localhost:8545)")
+	fromBlockFlag := flag.Uint64("from-block", 0, fmt.Sprintf("First block to scan. (default: 0, the last %d blocks)", arbitrage.HISTORY_DEFAULT_BLOCKS))
+	jsonFlag := flag.Bool("json", false, "Print the distributions as JSON instead of a table")
+	flag.Parse()
+
+	if *nodeAddressFlag == "" {
+		return input{}, errors.New("\"--node-address\" is required")
+	}
+
+	nodeAddress, err := arbitrage.ParseAddress(slog.Default(), "node", *nodeAddressFlag)
+	if err != nil {
+		return input{}, err
+	}
+
+	return input{
+		nodeAddress: nodeAddress,
+		rpcUrl:      *rpcFlag,
+		fromBlock:   *fromBlockFlag,
+		json:        *jsonFlag,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"rocketpoolArbitrage/arbitrage"
+)
+
+func main() {
+	logFile, err := parseInput()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	records, err := arbitrage.ReadGasLog(logFile)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	included := 0
+	for _, record := range records {
+		if record.Included {
+			included++
+		}
+	}
+	fmt.Printf("Read %d bundles from %s, %d were included.\n\n", len(records), logFile, included)
+
+	stats := arbitrage.GetGasStats(records)
+	if len(stats) == 0 {
+		fmt.Println("No included transactions with simulated and actual gas yet.")
+		return
+	}
+
+	fmt.Println("Estimation error (actual vs. simulated gas, positive means more gas was used on chain):")
+	fmt.Printf("    %-10s %6s %12s %12s %16s %16s\n", "Type", "Count", "Mean Error", "Median Error", "Mean Limit Used", "Max Limit Used")
+	for _, stat := range stats {
+		fmt.Printf("    %-10s %6d %11.2f%% %11.2f%% %15.1f%% %15.1f%%\n",
+			stat.Type,
+			stat.Count,
+			stat.MeanErrorPct,
+			stat.MedianErrorPct,
+			stat.MeanLimitUsagePct,
+			stat.MaxLimitUsagePct,
+		)
+	}
+}
+
+func parseInput() (string, error) {
+	logFileFlag := flag.String("log-file", "", "Log file written by distribute --log-file")
+	flag.Parse()
+
+	if *logFileFlag == "" {
+		return "", errors.New("\"--log-file\" is required")
+	}
+
+	return *logFileFlag, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"rocketpoolArbitrage/arbitrage"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+type input struct {
+	safe        common.Address
+	nodeAddress common.Address
+	minipools   []common.Address
+	rpcUrl      string
+	ratelimit   int
+	output      string
+}
+
+func main() {
+	in, err := parseInput()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ctx := context.Background()
+	client, err := ethclient.DialContext(ctx, in.rpcUrl)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	networkID, err := client.NetworkID(ctx)
+	if err != nil {
+		fmt.Println(errors.Join(errors.New("failed to verify client connection"), err))
+		return
+	}
+
+	// same selection as the distribute tool, only staking V3 minipools that exited and are not finalised
+	minipools := in.minipools
+	if len(minipools) == 0 {
+		dataIn := &arbitrage.DataIn{
+			Client:      client,
+			NetworkId:   networkID.Uint64(),
+			NodeAddress: &in.nodeAddress,
+			Ratelimit:   in.ratelimit,
+		}
+		minipools, err = arbitrage.GetDistributableNodeMinipools(ctx, slog.Default(), dataIn)
+		if err != nil {
+			fmt.Println(errors.Join(errors.New("failed to get the node's minipools"), err))
+			return
+		}
+		if len(minipools) == 0 {
+			fmt.Println(arbitrage.ErrNothingToDistribute)
+			return
+		}
+	}
+
+	batch, err := arbitrage.BuildSafeBatch(ctx, client, networkID.Uint64(), in.safe, in.nodeAddress, minipools, in.ratelimit)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = arbitrage.WriteSafeBatch(in.output, batch)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// stdout only carries the batch
+	if in.output != "-" {
+		fmt.Printf("Wrote %d distribute calls for safe %s to %s.\n", len(batch.Transactions), in.safe.Hex(), in.output)
+		fmt.Println("Load the file in the Transaction Builder app of the safe, then sign and execute it with the required owners.")
+	}
+}
+
+func parseInput() (input, error) {
+	safeFlag := flag.String("safe", "", "Safe multisig set as the withdrawal address of the node, it executes the distribute calls")
+	nodeAddressFlag := flag.String("node-address", "", "Node address owning the minipools")
+	minipoolsFlag := flag.String("minipools", "", "Comma-separated list of minipool addresses to distribute. (default: all distributable minipools of the node)")
+	rpcFlag := flag.String("rpc", "http://localhost:8545", "Ethereum RPC endpoint for all on-chain calls. (default: http://localhost:8545)")
+	ratelimitFlag := flag.Int("ratelimit", 0, "Rate limit in milliseconds between each RPC call. (default: 0)")
+	outputFlag := flag.String("output", "-", "File for the Transaction Builder batch, - for stdout. (default: -)")
+	flag.Parse()
+
+	logger := slog.Default()
+	if *safeFlag == "" || *nodeAddressFlag == "" {
+		return input{}, errors.New("\"--safe\" and \"--node-address\" are required")
+	}
+
+	safe, err := arbitrage.ParseAddress(logger, "safe", *safeFlag)
+	if err != nil {
+		return input{}, err
+	}
+
+	nodeAddress, err := arbitrage.ParseAddress(logger, "node", *nodeAddressFlag)
+	if err != nil {
+		return input{}, err
+	}
+
+	var minipools []common.Address
+	if *minipoolsFlag != "" {
+		for _, minipool := range strings.Split(*minipoolsFlag, ",") {
+			minipoolAddress, err := arbitrage.ParseAddress(logger, "minipool", minipool)
+			if err != nil {
+				return input{}, err
+			}
+			minipools = append(minipools, minipoolAddress)
+		}
+	}
+
+	if *outputFlag == "" {
+		return input{}, errors.New("\"--output\" must not be empty")
+	}
+	if *ratelimitFlag < 0 {
+		return input{}, errors.New("\"--ratelimit\" must not be negative")
+	}
+
+	return input{
+		safe:        safe,
+		nodeAddress: nodeAddress,
+		minipools:   minipools,
+		rpcUrl:      *rpcFlag,
+		ratelimit:   *ratelimitFlag,
+		output:      *outputFlag,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package uniswap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// minimal uniswap v3 pool ABI, only the view functions needed here
+const PoolABI = `[{"inputs":[],"name":"slot0","outputs":[{"internalType":"uint160","name":"sqrtPriceX96","type":"uint160"},{"internalType":"int24","name":"tick","type":"int24"},{"internalType":"uint16","name":"observationIndex","type":"uint16"},{"internalType":"uint16","name":"observationCardinality","type":"uint16"},{"internalType":"uint16","name":"observationCardinalityNext","type":"uint16"},{"internalType":"uint8","name":"feeProtocol","type":"uint8"},{"internalType":"bool","name":"unlocked","type":"bool"}],"stateMutability":"view","type":"function"}]`
+
+// GetPoolFee returns the fee tier of one of the supported rETH/WETH pools
+func GetPoolFee(pool common.Address) (*big.Int, error) {
+	switch pool {
+	case common.HexToAddress(PoolA):
+		return big.NewInt(100), nil
+	case common.HexToAddress(PoolB):
+		return big.NewInt(500), nil
+	default:
+		return nil, fmt.Errorf("unknown pool %s", pool.Hex())
+	}
+}
+
+// GetPoolPrice returns the current pool price in WETH per rETH
+func GetPoolPrice(ctx context.Context, client *ethclient.Client, pool common.Address, ratelimit int) (float64, error) {
+	poolABI, err := abi.JSON(strings.NewReader(PoolABI))
+	if err != nil {
+		return 0, errors.Join(errors.New("failed to get pool ABI"), err)
+	}
+
+	callData, err := poolABI.Pack("slot0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack function data: %v", err)
+	}
+
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &pool, Data: callData}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make static call: %v", err)
+	}
+	if ratelimit > 0 {
+		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
+	}
+
+	values, err := poolABI.Unpack("slot0", output)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack output: %v", err)
+	}
+
+	sqrtPriceX96, ok := values[0].(*big.Int)
+	if !ok {
+		return 0, errors.New("unexpected slot0 output")
+	}
+
+	return SqrtPriceX96ToPrice(sqrtPriceX96), nil
+}
+
+// GetPoolPriceAfterWithdrawArb returns the pool price in WETH per rETH after buying amount rETH from the pool
+func GetPoolPriceAfterWithdrawArb(ctx context.Context, client *ethclient.Client, pool common.Address, amount *big.Int, ratelimit int) (float64, error) {
+	fee, err := GetPoolFee(pool)
+	if err != nil {
+		return 0, err
+	}
+
+	// withdraw swaps => zeroForOne = false
+	result, err := quoteExactOutputSingle(ctx, client, false, amount, fee, ratelimit)
+	if err != nil {
+		return 0, err
+	}
+
+	return SqrtPriceX96ToPrice(result.SqrtPriceX96After), nil
+}
+
+// SqrtPriceX96ToPrice converts a uniswap sqrt price to token1 per token0, which is WETH per rETH for the rETH/WETH pools
+func SqrtPriceX96ToPrice(sqrtPriceX96 *big.Int) float64 {
+	sqrtPrice := new(big.Float).Quo(new(big.Float).SetInt(sqrtPriceX96), UniswapQ96)
+	price, _ := new(big.Float).Mul(sqrtPrice, sqrtPrice).Float64()
+	return price
+}
@@ -101,6 +101,26 @@ func GetBestPool(
 }
 
 func getExactOutput(ctx context.Context, client *ethclient.Client, zeroForOne bool, amount, fee, limit *big.Int, ratelimit int) (*big.Int, error) {
+	result, err := quoteExactOutputSingle(ctx, client, zeroForOne, amount, fee, ratelimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.SqrtPriceX96After.Cmp(limit) > 0 {
+		return nil, ErrPriceLimitExceeded
+	}
+
+	return result.AmountIn, nil
+}
+
+type exactOutputSingleResult struct {
+	AmountIn                *big.Int
+	SqrtPriceX96After       *big.Int
+	InitializedTicksCrossed uint32
+	GasEstimate             *big.Int
+}
+
+func quoteExactOutputSingle(ctx context.Context, client *ethclient.Client, zeroForOne bool, amount, fee *big.Int, ratelimit int) (*exactOutputSingleResult, error) {
 	quoterABI, err := abi.JSON(strings.NewReader(helper.HelperABI))
 	if err != nil {
 		return nil, errors.Join(errors.New("failed to get Quoter ABI"), err)
@@ -146,22 +166,13 @@ func getExactOutput(ctx context.Context, client *ethclient.Client, zeroForOne bo
 		time.Sleep(time.Duration(ratelimit) * time.Millisecond)
 	}
 
-	var result struct {
-		AmountIn                *big.Int
-		SqrtPriceX96After       *big.Int
-		InitializedTicksCrossed uint32
-		GasEstimate             *big.Int
-	}
+	var result exactOutputSingleResult
 	err = quoterABI.UnpackIntoInterface(&result, "quoteExactOutputSingle", output)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unpack output: %v", err)
 	}
 
-	if result.SqrtPriceX96After.Cmp(limit) > 0 {
-		return nil, ErrPriceLimitExceeded
-	}
-
-	return result.AmountIn, nil
+	return &result, nil
 }
 
 func getExactInput(ctx context.Context, client *ethclient.Client, zeroForOne bool, amount, fee, limit *big.Int, ratelimit int) (*big.Int, error) {